@@ -3,6 +3,8 @@ package logftext
 import (
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ssgreg/logf"
 )
@@ -10,18 +12,168 @@ import (
 // NewAppender returns a new logf.WriteAppender with the given Writer and
 // EncoderConfig.
 //
-// NewAppender is safe to use for colored logs.
+// NewAppender is safe to use for colored logs. If ColorMode is ColorAuto,
+// the decision is made in the following order:
+//
+// 	1. Environment variables, see ColorModeFromEnv.
+// 	2. Colors are enabled if the Writer is a TTY and disabled if it's
+// 	   a File bound to something else (e.g. a pipe or a regular file)
+// 	   or the terminal is dumb, see DetectTermCaps.
+//
+// Colors enabled explicitly with ColorAlways or environment variables are
+// used even if the terminal is dumb, see ForceColors.
+//
+// If no Theme is specified, NewAppender chooses the one that matches
+// a terminal background. The terminal is queried on the first Append, so
+// NewAppender itself never blocks. If Overflow or CallerRight is specified without
+// Width, the terminal width is used, see TerminalWidth.
 func NewAppender(w io.Writer, cfg EncoderConfig) logf.Appender {
+	return newAppender(w, cfg, DetectBackground)
+}
+
+func newAppender(w io.Writer, cfg EncoderConfig, detectBackground func(*os.File) Background) logf.Appender {
+	if cfg.ColorMode == ColorAuto {
+		cfg.ColorMode = ColorModeFromEnv()
+	}
+
 	if f, ok := w.(*os.File); ok {
 		ok = EnableSeqTTY(f, true)
 
-		if cfg.NoColor == nil {
-			// Set NoColor mode if Writer is not TTY or NO_COLOR environment
-			// variable is set.
-			noColor := !ok || CheckNoColor()
-			cfg.NoColor = &noColor
+		if cfg.TermCaps == nil {
+			caps := DetectTermCaps()
+			if cfg.ColorMode == ColorAlways {
+				// Colors forced explicitly win over a dumb terminal.
+				caps = ForceColors(caps)
+			}
+			cfg.TermCaps = &caps
+		}
+
+		if cfg.ColorMode == ColorAuto {
+			if ok && !cfg.TermCaps.Dumb() {
+				cfg.ColorMode = ColorAlways
+			} else {
+				cfg.ColorMode = ColorNever
+			}
+		}
+
+		if cfg.Width == 0 && (cfg.Overflow != OverflowNone || cfg.CallerPosition == CallerRight) && ok {
+			cfg.Width = TerminalWidth(f)
+		}
+
+		if cfg.Theme == nil && ok && cfg.ColorMode != ColorNever {
+			// Choose a theme that matches a terminal background. The
+			// terminal is queried on the first use of the appender since it
+			// can take a while.
+			return &lazyAppender{create: func() logf.Appender {
+				cfg.Theme = detectBackground(f).Theme()
+
+				return logf.NewWriteAppender(w, NewEncoder(cfg))
+			}}
 		}
 	}
 
 	return logf.NewWriteAppender(w, NewEncoder(cfg))
 }
+
+// lazyAppender creates the underlying appender on the first Append.
+type lazyAppender struct {
+	once     sync.Once
+	created  int32
+	create   func() logf.Appender
+	appender logf.Appender
+}
+
+func (a *lazyAppender) Append(e logf.Entry) error {
+	a.once.Do(func() {
+		a.appender = a.create()
+		atomic.StoreInt32(&a.created, 1)
+	})
+
+	return a.appender.Append(e)
+}
+
+func (a *lazyAppender) Flush() error {
+	if atomic.LoadInt32(&a.created) == 0 {
+		return nil
+	}
+
+	return a.appender.Flush()
+}
+
+func (a *lazyAppender) Sync() error {
+	if atomic.LoadInt32(&a.created) == 0 {
+		return nil
+	}
+
+	return a.appender.Sync()
+}
+
+// NewSplitAppender returns a new logf.Appender that writes entries with
+// LevelWarn and LevelError to errW and all other entries to w. Each Writer
+// gets its own appender created like with NewAppender, so colors are
+// enabled only for Writers that are terminals. A terminal background is
+// detected once for both Writers.
+func NewSplitAppender(w, errW io.Writer, cfg EncoderConfig) logf.Appender {
+	var once sync.Once
+	var bg Background
+	detectBackground := func(f *os.File) Background {
+		once.Do(func() {
+			bg = DetectBackground(f)
+		})
+
+		return bg
+	}
+
+	return &splitAppender{
+		out: newAppender(w, cfg, detectBackground),
+		err: newAppender(errW, cfg, detectBackground),
+	}
+}
+
+// NewStdAppender returns a new logf.Appender that writes warnings and
+// errors to os.Stderr and all other entries to os.Stdout.
+func NewStdAppender(cfg EncoderConfig) logf.Appender {
+	return NewSplitAppender(os.Stdout, os.Stderr, cfg)
+}
+
+type splitAppender struct {
+	out  logf.Appender
+	err  logf.Appender
+	last logf.Appender
+}
+
+func (a *splitAppender) Append(e logf.Entry) error {
+	next := a.out
+	if e.Level <= logf.LevelWarn {
+		next = a.err
+	}
+
+	// Keep the order of entries if both Writers are bound to the same
+	// terminal.
+	if a.last != nil && a.last != next {
+		if err := a.last.Flush(); err != nil {
+			return err
+		}
+	}
+	a.last = next
+
+	return next.Append(e)
+}
+
+func (a *splitAppender) Flush() error {
+	err := a.out.Flush()
+	if errErr := a.err.Flush(); err == nil {
+		err = errErr
+	}
+
+	return err
+}
+
+func (a *splitAppender) Sync() error {
+	err := a.out.Sync()
+	if errErr := a.err.Sync(); err == nil {
+		err = errErr
+	}
+
+	return err
+}
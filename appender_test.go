@@ -0,0 +1,85 @@
+package logftext
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/require"
+)
+
+// orderedWriter writes to the given Buffer and records the order of writes.
+type orderedWriter struct {
+	name  string
+	buf   *bytes.Buffer
+	order *[]string
+}
+
+func (w orderedWriter) Write(p []byte) (int, error) {
+	*w.order = append(*w.order, w.name)
+
+	return w.buf.Write(p)
+}
+
+func TestSplitAppender(t *testing.T) {
+	var order []string
+	out, errOut := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	a := NewSplitAppender(orderedWriter{"out", out, &order}, orderedWriter{"err", errOut, &order}, EncoderConfig{
+		ColorMode: ColorNever,
+	})
+	for _, e := range []logf.Entry{
+		{Level: logf.LevelDebug, Text: "1"},
+		{Level: logf.LevelInfo, Text: "2"},
+		{Level: logf.LevelWarn, Text: "3"},
+		{Level: logf.LevelError, Text: "4"},
+		{Level: logf.LevelInfo, Text: "5"},
+	} {
+		require.NoError(t, a.Append(e))
+	}
+	require.NoError(t, a.Flush())
+	require.NoError(t, a.Sync())
+
+	require.Equal(t, "Jan  1 00:00:00.000 |DEBU| 1\nJan  1 00:00:00.000 |INFO| 2\nJan  1 00:00:00.000 |INFO| 5\n", out.String())
+	require.Equal(t, "Jan  1 00:00:00.000 |WARN| 3\nJan  1 00:00:00.000 |ERRO| 4\n", errOut.String())
+	require.Equal(t, []string{"out", "err", "out"}, order)
+}
+
+func TestAppenderForcedColorsWithDumbTerminal(t *testing.T) {
+	defer setEnv([]string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "COLORTERM", "TERM"}, map[string]string{"TERM": "dumb", "FORCE_COLOR": "1"})()
+
+	f, err := ioutil.TempFile("", "logftext")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	a := NewAppender(f, EncoderConfig{})
+	require.NoError(t, a.Append(logf.Entry{Level: logf.LevelInfo, Text: "message"}))
+	require.NoError(t, a.Flush())
+
+	data, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+	require.Contains(t, string(data), "\x1b[36mINFO\x1b[0m")
+}
+
+func TestLazyAppender(t *testing.T) {
+	created := 0
+	out := bytes.NewBuffer(nil)
+	a := &lazyAppender{create: func() logf.Appender {
+		created++
+
+		return logf.NewWriteAppender(out, NewEncoder(EncoderConfig{ColorMode: ColorNever}))
+	}}
+
+	require.NoError(t, a.Flush())
+	require.NoError(t, a.Sync())
+	require.Equal(t, 0, created)
+
+	require.NoError(t, a.Append(logf.Entry{Level: logf.LevelInfo, Text: "1"}))
+	require.NoError(t, a.Append(logf.Entry{Level: logf.LevelInfo, Text: "2"}))
+	require.NoError(t, a.Flush())
+	require.Equal(t, 1, created)
+	require.Equal(t, "Jan  1 00:00:00.000 |INFO| 1\nJan  1 00:00:00.000 |INFO| 2\n", out.String())
+}
@@ -0,0 +1,123 @@
+package logftext
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Background represents a background color of a terminal.
+type Background int8
+
+// Possible Background values.
+const (
+	BackgroundUnknown Background = iota
+	BackgroundDark
+	BackgroundLight
+)
+
+// Theme returns the built-in theme suitable for the Background or nil if
+// the Background is unknown.
+func (b Background) Theme() *Theme {
+	switch b {
+	case BackgroundDark:
+		return DarkTheme()
+	case BackgroundLight:
+		return LightTheme()
+	}
+
+	return nil
+}
+
+// DetectBackground detects a background color of a terminal the given File
+// is bound to.
+//
+// First it queries the terminal with OSC 11 escape sequence. If the File is
+// not a terminal, the process is not in the terminal foreground (e.g. it's
+// started with "&") or the terminal does not respond, DetectBackground
+// falls back to COLORFGBG environment variable.
+func DetectBackground(f *os.File) Background {
+	if enableSeqTTY(f.Fd(), true) == nil {
+		if response, err := queryTTY(osc11Query); err == nil {
+			if bg := parseOSC11Response(response); bg != BackgroundUnknown {
+				return bg
+			}
+		}
+	}
+
+	return parseColorFGBG(os.Getenv("COLORFGBG"))
+}
+
+// osc11Query requests a terminal background color. It is followed by the
+// primary device attributes request (DA1) which is supported by all
+// terminals. That allows not to wait for a response from terminals that
+// do not support OSC 11.
+const osc11Query = "\x1b]11;?\x07\x1b[c"
+
+// parseOSC11Response parses the terminal response in the following form:
+// "ESC ] 11 ; rgb:RRRR/GGGG/BBBB BEL". ST can be used instead of BEL.
+func parseOSC11Response(response []byte) Background {
+	found := bytes.Index(response, []byte("\x1b]11;rgb:"))
+	if found == -1 {
+		return BackgroundUnknown
+	}
+	response = response[found+len("\x1b]11;rgb:"):]
+
+	end := bytes.IndexAny(response, "\x07\x1b")
+	if end == -1 {
+		return BackgroundUnknown
+	}
+
+	parts := strings.Split(string(response[:end]), "/")
+	if len(parts) != 3 {
+		return BackgroundUnknown
+	}
+
+	var rgb [3]float64
+	for i, part := range parts {
+		if len(part) == 0 || len(part) > 4 {
+			return BackgroundUnknown
+		}
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil {
+			return BackgroundUnknown
+		}
+		// Each component is specified with 1 to 4 hex digits.
+		rgb[i] = float64(v) / float64(uint64(1)<<(4*uint(len(part)))-1)
+	}
+
+	return backgroundFromLuminance(0.2126*rgb[0] + 0.7152*rgb[1] + 0.0722*rgb[2])
+}
+
+func backgroundFromLuminance(luminance float64) Background {
+	if luminance > 0.5 {
+		return BackgroundLight
+	}
+
+	return BackgroundDark
+}
+
+// parseColorFGBG parses the value of COLORFGBG environment variable set by
+// some terminals (e.g. rxvt, Konsole, iTerm2) in the following form:
+// "fg;bg" or "fg;default;bg", where fg and bg are ANSI color numbers.
+func parseColorFGBG(value string) Background {
+	found := strings.LastIndexByte(value, ';')
+	if found == -1 {
+		return BackgroundUnknown
+	}
+
+	bg, err := strconv.Atoi(value[found+1:])
+	if err != nil {
+		return BackgroundUnknown
+	}
+
+	switch {
+	case bg == 7 || (bg >= 9 && bg <= 15):
+		return BackgroundLight
+	case bg >= 0 && bg <= 8:
+		return BackgroundDark
+	}
+
+	return BackgroundUnknown
+}
@@ -0,0 +1,25 @@
+package logftext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOSC11Response(t *testing.T) {
+	require.Equal(t, BackgroundDark, parseOSC11Response([]byte("\x1b]11;rgb:0000/0000/0000\x07\x1b[?62;22c")))
+	require.Equal(t, BackgroundLight, parseOSC11Response([]byte("\x1b]11;rgb:ffff/ffff/ffff\x1b\\")))
+	require.Equal(t, BackgroundLight, parseOSC11Response([]byte("\x1b]11;rgb:fd/f6/e3\x07")))
+	require.Equal(t, BackgroundDark, parseOSC11Response([]byte("\x1b]11;rgb:2828/2c2c/3434\x07")))
+	require.Equal(t, BackgroundUnknown, parseOSC11Response([]byte("\x1b[?62;22c")))
+	require.Equal(t, BackgroundUnknown, parseOSC11Response([]byte("\x1b]11;rgb:zz/00/00\x07")))
+	require.Equal(t, BackgroundUnknown, parseOSC11Response([]byte("\x1b]11;rgb:00/00\x07")))
+}
+
+func TestParseColorFGBG(t *testing.T) {
+	require.Equal(t, BackgroundDark, parseColorFGBG("15;0"))
+	require.Equal(t, BackgroundLight, parseColorFGBG("0;15"))
+	require.Equal(t, BackgroundLight, parseColorFGBG("0;default;7"))
+	require.Equal(t, BackgroundUnknown, parseColorFGBG("0;default"))
+	require.Equal(t, BackgroundUnknown, parseColorFGBG(""))
+}
@@ -0,0 +1,183 @@
+package logftext
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCacheSize is the size of the Cache created by Encoder if no
+// EncoderConfig.Cache is specified.
+const DefaultCacheSize = 100
+
+// Cache is an LRU cache of encoded logger's fields. Logger's fields are
+// encoded once for each logger and each Encoder configuration that
+// affects their appearance, so the Cache can be safely shared between
+// Encoders, e.g. a colored one writing to a terminal and a plain one
+// writing to a file. Encoders with custom EncodeTime, EncodeDuration,
+// EncodeError or FilterKey never share cached fields since functions
+// can't be compared. The Cache is safe for concurrent use.
+type Cache struct {
+	// hits and misses go first to be 64-bit aligned for atomic operations.
+	hits   uint64
+	misses uint64
+
+	mu      sync.Mutex
+	m       map[cacheKey]*list.Element
+	l       *list.List
+	limit   int
+	configs map[cacheConfig]uint32
+	ids     uint32
+}
+
+// CacheStats holds Cache usage statistics.
+type CacheStats struct {
+	// Hits and Misses are the numbers of lookups that found and did not
+	// find cached fields respectively.
+	Hits   uint64
+	Misses uint64
+
+	// Len is the number of cached entries.
+	Len int
+}
+
+// NewCache returns a new Cache that holds up to the given number of
+// entries.
+func NewCache(limit int) *Cache {
+	if limit < 1 {
+		limit = 1
+	}
+
+	return &Cache{
+		m:       make(map[cacheKey]*list.Element, limit),
+		l:       list.New(),
+		limit:   limit,
+		configs: make(map[cacheConfig]uint32),
+	}
+}
+
+// Stats returns the Cache usage statistics.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	n := len(c.m)
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+		Len:    n,
+	}
+}
+
+// Clean removes all entries from the Cache.
+func (c *Cache) Clean() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.l = list.New()
+	c.m = make(map[cacheKey]*list.Element, c.limit)
+}
+
+// cacheConfig holds everything that affects the appearance of encoded
+// fields. Encoders with equal cacheConfig share cached fields.
+type cacheConfig struct {
+	pal           palette
+	format        Format
+	expanded      bool
+	unquote       bool
+	formatObjects bool
+	indentObjects bool
+	expandErrors  bool
+	expandStacks  bool
+	orderFields   bool
+
+	// allowKeys, denyKeys and redactKeys hold key patterns separated with
+	// zero bytes since slices can't be compared.
+	allowKeys  string
+	denyKeys   string
+	redactKeys string
+}
+
+// cacheKey identifies logger's fields encoded with a specific
+// configuration.
+type cacheKey struct {
+	config uint32
+	logger int32
+}
+
+type cacheElement struct {
+	key   cacheKey
+	bytes []byte
+	ends  []int
+}
+
+// maxCacheConfigs limits the number of configurations remembered by
+// Cache. Encoders with other configurations get unique identifiers.
+const maxCacheConfigs = 1000
+
+// configID returns the identifier of the given configuration. Equal
+// configurations have equal identifiers unless custom is set, which means
+// the configuration has functions and is unique.
+func (c *Cache) configID(cfg cacheConfig, custom bool) uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if custom || len(c.configs) >= maxCacheConfigs {
+		c.ids++
+
+		return c.ids
+	}
+
+	id, ok := c.configs[cfg]
+	if !ok {
+		c.ids++
+		id = c.ids
+		c.configs[cfg] = id
+	}
+
+	return id
+}
+
+// get returns cached fields for the given key and ends of each field if
+// they were set. Cached buffers are never modified, so the returned ones
+// are safe to use without the lock.
+func (c *Cache) get(k cacheKey) ([]byte, []int, bool) {
+	c.mu.Lock()
+	var el cacheElement
+	e, ok := c.m[k]
+	if ok {
+		c.l.MoveToFront(e)
+		el = *e.Value.(*cacheElement)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+
+		return nil, nil, false
+	}
+	atomic.AddUint64(&c.hits, 1)
+
+	return el.bytes, el.ends, true
+}
+
+// set adds the given fields with the given key and, optionally, ends of
+// each field to the Cache or replaces the existing ones.
+func (c *Cache) set(k cacheKey, bytes []byte, ends []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.m[k]; ok {
+		el := e.Value.(*cacheElement)
+		el.bytes, el.ends = bytes, ends
+		c.l.MoveToFront(e)
+
+		return
+	}
+	c.m[k] = c.l.PushFront(&cacheElement{k, bytes, ends})
+
+	if c.l.Len() > c.limit {
+		e := c.l.Remove(c.l.Back())
+		delete(c.m, e.(*cacheElement).key)
+	}
+}
@@ -0,0 +1,87 @@
+package logftext
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheSharedBetweenEncoders(t *testing.T) {
+	cache := NewCache(10)
+	colored := NewEncoder(EncoderConfig{Cache: cache, Theme: &Theme{Key: NewStyle(EscGreen)}})
+	plain := NewEncoder(EncoderConfig{Cache: cache, ColorMode: ColorNever})
+	plainToo := NewEncoder(EncoderConfig{Cache: cache, ColorMode: ColorNever})
+
+	e := logf.Entry{
+		LoggerID:      1,
+		Level:         logf.LevelInfo,
+		Text:          "message",
+		DerivedFields: []logf.Field{logf.Int("a", 1)},
+	}
+
+	b := logf.NewBuffer()
+	require.NoError(t, colored.Encode(b, e))
+	require.Equal(t, "Jan  1 00:00:00.000 |INFO| message \x1b[32ma\x1b[0m=1\n", b.String())
+
+	b.Reset()
+	require.NoError(t, plain.Encode(b, e))
+	require.Equal(t, "Jan  1 00:00:00.000 |INFO| message a=1\n", b.String())
+
+	b.Reset()
+	require.NoError(t, plainToo.Encode(b, e))
+	require.Equal(t, "Jan  1 00:00:00.000 |INFO| message a=1\n", b.String())
+
+	require.Equal(t, CacheStats{Hits: 1, Misses: 2, Len: 2}, cache.Stats())
+}
+
+func TestCacheCustomEncoders(t *testing.T) {
+	cache := NewCache(10)
+	seconds := NewEncoder(EncoderConfig{Cache: cache, ColorMode: ColorNever, EncodeDuration: logf.FloatSecondsDurationEncoder})
+	strings := NewEncoder(EncoderConfig{Cache: cache, ColorMode: ColorNever, EncodeDuration: logf.StringDurationEncoder})
+
+	e := logf.Entry{
+		LoggerID:      1,
+		Level:         logf.LevelInfo,
+		Text:          "message",
+		DerivedFields: []logf.Field{logf.Duration("d", time.Second)},
+	}
+
+	b := logf.NewBuffer()
+	require.NoError(t, seconds.Encode(b, e))
+	require.Equal(t, "Jan  1 00:00:00.000 |INFO| message d=1\n", b.String())
+
+	b.Reset()
+	require.NoError(t, strings.Encode(b, e))
+	require.Equal(t, "Jan  1 00:00:00.000 |INFO| message d=\"1s\"\n", b.String())
+}
+
+func TestCacheConfigsLimit(t *testing.T) {
+	cache := NewCache(10)
+	for i := 0; i < 10; i++ {
+		NewEncoder(EncoderConfig{Cache: cache, FilterKey: func(string) Action { return ActionKeep }})
+	}
+	require.Len(t, cache.configs, 0)
+
+	for i := 0; i < maxCacheConfigs+10; i++ {
+		NewEncoder(EncoderConfig{Cache: cache, RedactKeys: []string{strconv.Itoa(i)}})
+	}
+	require.Len(t, cache.configs, maxCacheConfigs)
+}
+
+func TestCacheLimit(t *testing.T) {
+	cache := NewCache(2)
+	enc := NewEncoder(EncoderConfig{Cache: cache, ColorMode: ColorNever})
+
+	b := logf.NewBuffer()
+	for _, id := range []int32{1, 2, 1, 3, 2} {
+		require.NoError(t, enc.Encode(b, logf.Entry{LoggerID: id, DerivedFields: []logf.Field{logf.Int32("id", id)}}))
+	}
+
+	require.Equal(t, CacheStats{Hits: 1, Misses: 4, Len: 2}, cache.Stats())
+
+	cache.Clean()
+	require.Equal(t, 0, cache.Stats().Len)
+}
@@ -0,0 +1,86 @@
+package logftext
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+
+	"github.com/ssgreg/logf"
+)
+
+// CallerPosition specifies where the caller is placed in the text format.
+type CallerPosition int8
+
+// Possible CallerPosition values.
+const (
+	// CallerAtEnd places the caller at the end of the line.
+	CallerAtEnd CallerPosition = iota
+
+	// CallerAfterLevel places the caller right after the level:
+	//
+	// 	Jan  1 00:00:00.000 |INFO| @c/f.go:6 main: message key="value"
+	CallerAfterLevel
+
+	// CallerRight aligns the caller to the right edge of a line of
+	// EncoderConfig.Width. It's placed at the end of the line if the Width
+	// is not specified or the line is too long.
+	CallerRight
+)
+
+// appendCaller appends the caller in form of "@file:line". The location
+// is quoted only if needed. The function name is added in parentheses if
+// CallerFunction is set.
+func (f *encoder) appendCaller(caller logf.EntryCaller) {
+	f.buf.AppendByte('@')
+
+	start := f.buf.Len()
+	f.EncodeCaller(caller, f.mf.TypeEncoder(f.buf))
+	v := f.buf.Data[start:]
+	if len(v) > 2 && v[0] == '"' && v[len(v)-1] == '"' && !needsQuoting(v[1:len(v)-1]) {
+		f.unquote(start)
+	}
+
+	if f.CallerFunction {
+		if fn := callerFunction(caller.PC); fn != "" {
+			f.buf.AppendString(" (")
+			f.buf.AppendString(fn)
+			f.buf.AppendByte(')')
+		}
+	}
+}
+
+// alignCaller moves the caller encoded starting from the given position
+// to the right edge of the line.
+func (f *encoder) alignCaller(start int) {
+	lineStart := f.startBufLen
+	if found := bytes.LastIndexByte(f.buf.Data[f.startBufLen:start], '\n'); found != -1 {
+		lineStart += found + 1
+	}
+
+	n := f.Width - visibleWidth(f.buf.Data[lineStart:])
+	if f.Width == 0 || n <= 0 {
+		return
+	}
+
+	f.scratch.Reset()
+	f.scratch.AppendBytes(f.buf.Data[start:])
+	f.buf.Data = f.buf.Data[:start]
+	appendPadding(f.buf, n)
+	f.buf.AppendBytes(f.scratch.Bytes())
+}
+
+// callerFunction returns the name of the function with the given program
+// counter without the package path, e.g. "logftext.(*encoder).Encode".
+func callerFunction(pc uintptr) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+
+	name := fn.Name()
+	if found := strings.LastIndexByte(name, '/'); found != -1 {
+		name = name[found+1:]
+	}
+
+	return name
+}
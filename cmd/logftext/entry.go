@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssgreg/logf"
+	"github.com/ssgreg/logftext"
+)
+
+// parseEntry parses a line written by logf JSON Encoder with default field
+// keys. The order of fields is preserved. JSON objects without level and
+// message are not considered to be entries.
+func parseEntry(line []byte) (logf.Entry, bool) {
+	var e logf.Entry
+	var hasLevel, hasMsg bool
+
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return e, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return e, false
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return e, false
+		}
+		key, ok := token.(string)
+		if !ok {
+			return e, false
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return e, false
+		}
+
+		if !parsePredefinedField(&e, key, raw) {
+			field, ok := logftext.ParseField(key, raw)
+			if !ok {
+				return e, false
+			}
+			e.Fields = append(e.Fields, field)
+
+			continue
+		}
+		switch key {
+		case logf.DefaultFieldKeyLevel:
+			hasLevel = true
+		case logf.DefaultFieldKeyMsg:
+			hasMsg = true
+		}
+	}
+
+	// Check the closing brace.
+	if _, err := dec.Token(); err != nil {
+		return e, false
+	}
+
+	return e, hasLevel && hasMsg
+}
+
+// parsePredefinedField fills the Entry with the field with the given key
+// if it's a predefined one.
+func parsePredefinedField(e *logf.Entry, key string, raw json.RawMessage) bool {
+	var s string
+
+	switch key {
+	case logf.DefaultFieldKeyTime:
+		t, ok := parseTime(raw)
+		if !ok {
+			return false
+		}
+		e.Time = t
+	case logf.DefaultFieldKeyLevel:
+		if json.Unmarshal(raw, &s) != nil {
+			return false
+		}
+		e.Level, _ = logftext.ParseLevel(s)
+	case logf.DefaultFieldKeyName:
+		if json.Unmarshal(raw, &e.LoggerName) != nil {
+			return false
+		}
+	case logf.DefaultFieldKeyMsg:
+		if json.Unmarshal(raw, &e.Text) != nil {
+			return false
+		}
+	case logf.DefaultFieldKeyCaller:
+		if json.Unmarshal(raw, &s) != nil {
+			return false
+		}
+		found := strings.LastIndexByte(s, ':')
+		if found == -1 {
+			return false
+		}
+		line, err := strconv.Atoi(s[found+1:])
+		if err != nil {
+			return false
+		}
+		e.Caller = logf.EntryCaller{File: s[:found], Line: line, Specified: true}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// parseTime parses time encoded as a RFC3339 string or a number of
+// seconds or nanoseconds since the Unix epoch.
+func parseTime(raw json.RawMessage) (time.Time, bool) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		t, err := time.Parse(time.RFC3339Nano, s)
+
+		return t, err == nil
+	}
+
+	// Nanoseconds are used by logf.UnixNanoTimeEncoder.
+	if ns, err := strconv.ParseInt(string(raw), 10, 64); err == nil && ns > 1e15 {
+		return time.Unix(0, ns), true
+	}
+
+	// Seconds and their fraction are parsed separately since float64 is
+	// not precise enough to keep nanoseconds, e.g. 1541775845.123 would
+	// become 1541775845.122999906.
+	if sec, frac := string(raw), ""; !strings.ContainsAny(sec, "-eE") {
+		if found := strings.IndexByte(sec, '.'); found != -1 {
+			sec, frac = sec[:found], (sec[found+1:] + "000000000")[:9]
+		}
+		n, err := strconv.ParseInt(sec, 10, 64)
+		ns, nsErr := strconv.ParseUint("0"+frac, 10, 32)
+		if err == nil && nsErr == nil {
+			return time.Unix(n, int64(ns)), true
+		}
+	}
+
+	// Negative numbers and exponent forms.
+	f, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(math.Round(f*float64(time.Second)))), true
+}
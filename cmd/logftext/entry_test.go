@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssgreg/logf"
+	"github.com/ssgreg/logftext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	input := strings.Join([]string{
+		`{"level":"info","ts":"2018-11-09T15:04:05.123Z","logger":"main","msg":"got cpu info","caller":"example/main.go:22","count":8,"ok":true,"s":"v","a":[1,2],"o":{"k":"v"},"n":null}`,
+		`not a json line`,
+		`{"level":"error","ts":"2018-11-09T15:04:06.789Z","msg":"failed","error":"failed to do nothing"}`,
+		`{"broken":`,
+		`{"foo":1}`,
+		`{"level":"info","foo":1}`,
+		`{"level":"fatal","msg":"m","n":1.5}`,
+	}, "\n") + "\n"
+
+	out := bytes.NewBuffer(nil)
+	appender := logftext.NewAppender(out, logftext.EncoderConfig{
+		ColorMode:  logftext.ColorNever,
+		EncodeTime: logf.LayoutTimeEncoder("15:04:05.000"),
+	})
+
+	require.NoError(t, convert(strings.NewReader(input), appender, out))
+	require.Equal(t, strings.Join([]string{
+		`15:04:05.123 |INFO| main: got cpu info count=8 ok=true s="v" a=[1,2] o={"k":"v"} n=null @example/main.go:22`,
+		`not a json line`,
+		`15:04:06.789 |ERRO| failed error="failed to do nothing"`,
+		`{"broken":`,
+		`{"foo":1}`,
+		`{"level":"info","foo":1}`,
+		`00:00:00.000 |UNKN| m n=1.5`,
+	}, "\n")+"\n", out.String())
+}
+
+func TestParseTime(t *testing.T) {
+	for raw, expected := range map[string]time.Time{
+		`"2018-11-09T15:04:05.123Z"`: time.Date(2018, time.November, 9, 15, 4, 5, 123000000, time.UTC),
+		`1541775845.123`:             time.Unix(1541775845, 123000000),
+		`1541775845`:                 time.Unix(1541775845, 0),
+		`1541775845123456789`:        time.Unix(1541775845, 123456789),
+		`1.5e9`:                      time.Unix(1500000000, 0),
+		`-1.5`:                       time.Unix(-2, 500000000),
+	} {
+		parsed, ok := parseTime(json.RawMessage(raw))
+		require.True(t, ok, raw)
+		require.True(t, expected.Equal(parsed), "%s: %v", raw, parsed)
+	}
+
+	_, ok := parseTime(json.RawMessage(`"yesterday"`))
+	require.False(t, ok)
+}
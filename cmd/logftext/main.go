@@ -0,0 +1,166 @@
+// Command logftext converts logs written by logf JSON Encoder to colored
+// text logs.
+//
+// Usage:
+//
+// 	logftext [flags] [file ...]
+//
+// It reads the given files or standard input if no files are given. Lines
+// that are not JSON objects with "level" and "msg" keys are passed through
+// untouched.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ssgreg/logf"
+	"github.com/ssgreg/logftext"
+)
+
+func main() {
+	var (
+		noColor       = flag.Bool("no-color", false, "disable colored output")
+		disableName   = flag.Bool("disable-name", false, "do not print logger names")
+		disableCaller = flag.Bool("disable-caller", false, "do not print callers")
+		unquote       = flag.Bool("unquote", false, "print simple strings without quotes")
+		stacks        = flag.Bool("stacks", false, "print stack traces one frame per line")
+		align         = flag.Bool("align", false, "align fields in a column")
+		expand        = flag.Int("expand", 0, "print fields on separate lines for entries with at least the given number of fields")
+		overflow      = flag.String("overflow", "none", "handling of lines wider than the terminal: none, wrap or truncate")
+		width         = flag.Int("width", 0, "maximum width of a line, the terminal width is used by default")
+		callerURL     = flag.String("caller-url", "", "URL template of caller hyperlinks, e.g. vscode://file{path}:{line}")
+		callerPos     = flag.String("caller-position", "end", "position of callers: end, level or right")
+		callerWidth   = flag.Int("caller-width", 0, "minimum width of callers printed after the level")
+		sortFields    = flag.Bool("sort", false, "sort fields by key")
+		firstKeys     = flag.String("first", "", "comma-separated keys or glob patterns of fields to print first")
+		lastKeys      = flag.String("last", "", "comma-separated keys or glob patterns of fields to print last")
+		redact        = flag.String("redact", "", "comma-separated keys or glob patterns of fields to redact")
+		drop          = flag.String("drop", "", "comma-separated keys or glob patterns of fields to drop")
+		timeLayout    = flag.String("time-layout", time.StampMilli, "layout of entry time, see time.Format")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file ...]\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg := logftext.EncoderConfig{
+		DisableFieldName:   *disableName,
+		DisableFieldCaller: *disableCaller,
+		AlignFields:        *align,
+		UnquoteStrings:     *unquote,
+		ExpandStacks:       *stacks,
+		SortFields:         *sortFields,
+		CallerURL:          *callerURL,
+		CallerWidth:        *callerWidth,
+		Width:              *width,
+		EncodeTime:         logf.LayoutTimeEncoder(*timeLayout),
+	}
+	if *firstKeys != "" {
+		cfg.FirstKeys = strings.Split(*firstKeys, ",")
+	}
+	if *lastKeys != "" {
+		cfg.LastKeys = strings.Split(*lastKeys, ",")
+	}
+	if *redact != "" {
+		cfg.RedactKeys = strings.Split(*redact, ",")
+	}
+	if *drop != "" {
+		cfg.DenyKeys = strings.Split(*drop, ",")
+	}
+	if *expand > 0 {
+		cfg.Layout = logftext.LayoutExpanded
+		cfg.ExpandFieldCount = *expand
+	}
+	switch *overflow {
+	case "none":
+	case "wrap":
+		cfg.Overflow = logftext.OverflowWrap
+	case "truncate":
+		cfg.Overflow = logftext.OverflowTruncate
+	default:
+		fmt.Fprintf(os.Stderr, "logftext: unknown overflow mode %q\n", *overflow)
+		os.Exit(2)
+	}
+	switch *callerPos {
+	case "end":
+	case "level":
+		cfg.CallerPosition = logftext.CallerAfterLevel
+	case "right":
+		cfg.CallerPosition = logftext.CallerRight
+	default:
+		fmt.Fprintf(os.Stderr, "logftext: unknown caller position %q\n", *callerPos)
+		os.Exit(2)
+	}
+	if *noColor {
+		cfg.ColorMode = logftext.ColorNever
+	}
+	appender := logftext.NewAppender(os.Stdout, cfg)
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	status := 0
+	for _, name := range files {
+		err := convertFile(name, appender, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logftext: %v\n", err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func convertFile(name string, appender logf.Appender, w io.Writer) error {
+	if name == "-" {
+		return convert(os.Stdin, appender, w)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return convert(f, appender, w)
+}
+
+// convert reads logf JSON lines from the given Reader and appends them to
+// the given Appender. Lines that are not JSON objects are written to the
+// given Writer as is.
+func convert(r io.Reader, appender logf.Appender, w io.Writer) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) != 0 {
+			if e, ok := parseEntry(line); ok {
+				if err := appender.Append(e); err != nil {
+					return err
+				}
+				if err := appender.Flush(); err != nil {
+					return err
+				}
+			} else {
+				if _, err := w.Write(line); err != nil {
+					return err
+				}
+			}
+		}
+
+		switch err {
+		case nil:
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package logftext
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ssgreg/logf"
+)
+
+// ColorLevel specifies the number of colors supported by a terminal.
+type ColorLevel int8
+
+// Possible ColorLevel values.
+const (
+	// ColorLevelAuto means the level is not specified. NewAppender detects
+	// it using DetectColorLevel.
+	ColorLevelAuto ColorLevel = iota
+	// ColorLevelNone means colors are not supported at all. Text attributes
+	// like bold or underline can still be used.
+	ColorLevelNone
+	// ColorLevel16 means only basic 16 colors are supported.
+	ColorLevel16
+	// ColorLevel256 means 256 colors are supported.
+	ColorLevel256
+	// ColorLevelTrueColor means 24-bit colors are supported.
+	ColorLevelTrueColor
+)
+
+// DetectColorLevel detects the color level supported by a terminal using
+// FORCE_COLOR, COLORTERM and TERM environment variables, see
+// LookupTermCaps. FORCE_COLOR values 1, 2 and 3 specify 16 colors, 256
+// colors and 24-bit colors respectively.
+func DetectColorLevel() ColorLevel {
+	switch os.Getenv("FORCE_COLOR") {
+	case "1":
+		return ColorLevel16
+	case "2":
+		return ColorLevel256
+	case "3":
+		return ColorLevelTrueColor
+	}
+
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorLevelTrueColor
+	}
+
+	return LookupTermCaps(os.Getenv("TERM")).Colors
+}
+
+// Extended color layout:
+//
+// 	bits 0-23  - 256-color index or 24-bit RGB value
+// 	bits 24-25 - kind of color
+// 	bit  26    - background flag
+const (
+	escKind256    EscapeCode = 1 << 24
+	escKindRGB    EscapeCode = 2 << 24
+	escKindMask   EscapeCode = 3 << 24
+	escBackground EscapeCode = 1 << 26
+)
+
+// Color256 returns the EscapeCode for the given text color from the
+// 256-color palette.
+func Color256(n uint8) EscapeCode {
+	return escKind256 | EscapeCode(n)
+}
+
+// BgColor256 returns the EscapeCode for the given background color from
+// the 256-color palette.
+func BgColor256(n uint8) EscapeCode {
+	return escBackground | Color256(n)
+}
+
+// RGB returns the EscapeCode for the given 24-bit text color.
+func RGB(r, g, b uint8) EscapeCode {
+	return escKindRGB | EscapeCode(r)<<16 | EscapeCode(g)<<8 | EscapeCode(b)
+}
+
+// BgRGB returns the EscapeCode for the given 24-bit background color.
+func BgRGB(r, g, b uint8) EscapeCode {
+	return escBackground | RGB(r, g, b)
+}
+
+// IsColor reports whether the EscapeCode changes a text or background
+// color.
+func (c EscapeCode) IsColor() bool {
+	if c&escKindMask != 0 {
+		return true
+	}
+
+	return (c >= 30 && c <= 49) || (c >= 90 && c <= 107)
+}
+
+// Downgrade converts the EscapeCode to the closest one supported within
+// the given ColorLevel. Basic codes are returned as is.
+func (c EscapeCode) Downgrade(level ColorLevel) EscapeCode {
+	switch level {
+	case ColorLevelAuto, ColorLevelTrueColor:
+		return c
+	}
+
+	bg := c & escBackground
+	switch c & escKindMask {
+	case escKindRGB:
+		r, g, b := c.rgb()
+		if level == ColorLevel256 {
+			return bg | Color256(rgbTo256(r, g, b))
+		}
+
+		return basicColor(rgbTo16(r, g, b), bg != 0)
+	case escKind256:
+		if level == ColorLevel256 {
+			return c
+		}
+		n := uint8(c)
+		if n < 16 {
+			return basicColor(n, bg != 0)
+		}
+
+		return basicColor(rgbTo16(color256ToRGB(n)), bg != 0)
+	}
+
+	return c
+}
+
+func (c EscapeCode) rgb() (uint8, uint8, uint8) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c)
+}
+
+func appendEscapeCode(buf *logf.Buffer, c EscapeCode) {
+	switch c & escKindMask {
+	case escKind256:
+		appendExtendedPrefix(buf, c, "5;")
+		logf.AppendUint(buf, uint64(uint8(c)))
+	case escKindRGB:
+		appendExtendedPrefix(buf, c, "2;")
+		r, g, b := c.rgb()
+		logf.AppendUint(buf, uint64(r))
+		buf.AppendByte(';')
+		logf.AppendUint(buf, uint64(g))
+		buf.AppendByte(';')
+		logf.AppendUint(buf, uint64(b))
+	default:
+		logf.AppendInt(buf, int64(c))
+	}
+}
+
+func appendExtendedPrefix(buf *logf.Buffer, c EscapeCode, kind string) {
+	if c&escBackground != 0 {
+		buf.AppendString("48;")
+	} else {
+		buf.AppendString("38;")
+	}
+	buf.AppendString(kind)
+}
+
+// basicColor returns the basic EscapeCode for the given color index from
+// the 16-color palette.
+func basicColor(n uint8, bg bool) EscapeCode {
+	c := EscBlack + EscapeCode(n)
+	if n >= 8 {
+		c = EscBrightBlack + EscapeCode(n-8)
+	}
+	if bg {
+		c += EscBgBlack - EscBlack
+	}
+
+	return c
+}
+
+// palette16 holds the default xterm values of the basic 16 colors.
+var palette16 = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels holds the component values of the 6x6x6 color cube of the
+// 256-color palette.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+func color256ToRGB(n uint8) (uint8, uint8, uint8) {
+	switch {
+	case n < 16:
+		return palette16[n][0], palette16[n][1], palette16[n][2]
+	case n < 232:
+		n -= 16
+
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	}
+	v := 8 + (n-232)*10
+
+	return v, v, v
+}
+
+func rgbTo256(r, g, b uint8) uint8 {
+	ri, gi, bi := cubeIndex(r), cubeIndex(g), cubeIndex(b)
+	cube := 16 + 36*ri + 6*gi + bi
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	grayIndex := 23
+	if avg < 238 {
+		grayIndex = (avg - 3) / 10
+		if grayIndex < 0 {
+			grayIndex = 0
+		}
+	}
+	gray := uint8(232 + grayIndex)
+
+	gr, gg, gb := color256ToRGB(gray)
+	cr, cg, cb := color256ToRGB(cube)
+	if colorDistance(r, g, b, gr, gg, gb) < colorDistance(r, g, b, cr, cg, cb) {
+		return gray
+	}
+
+	return cube
+}
+
+func cubeIndex(v uint8) uint8 {
+	switch {
+	case v < 48:
+		return 0
+	case v < 115:
+		return 1
+	}
+
+	return (v - 35) / 40
+}
+
+func rgbTo16(r, g, b uint8) uint8 {
+	best, bestDistance := 0, -1
+	for i, p := range palette16 {
+		d := colorDistance(r, g, b, p[0], p[1], p[2])
+		if bestDistance == -1 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+
+	return uint8(best)
+}
+
+func colorDistance(r1, g1, b1, r2, g2, b2 uint8) int {
+	dr := int(r1) - int(r2)
+	dg := int(g1) - int(g2)
+	db := int(b1) - int(b2)
+
+	return dr*dr + dg*dg + db*db
+}
@@ -0,0 +1,56 @@
+package logftext
+
+import (
+	"os"
+	"strings"
+)
+
+// ColorMode specifies whether to use colors in the output.
+type ColorMode int8
+
+// Possible ColorMode values.
+const (
+	// ColorAuto leaves the decision to NewAppender. The Encoder itself
+	// treats ColorAuto as ColorAlways.
+	ColorAuto ColorMode = iota
+	// ColorAlways enables colored output.
+	ColorAlways
+	// ColorNever disables colored output.
+	ColorNever
+)
+
+// ColorModeFromEnv checks the well-known environment variables to decide
+// whether colors are explicitly requested or prohibited. The variables are
+// checked in the following order, the first one that is set wins:
+//
+// 	NO_COLOR        - any value disables colors
+// 	FORCE_COLOR     - "0" or "false" disables colors, any other value
+// 	                  enables them
+// 	CLICOLOR_FORCE  - any value except "0" enables colors
+// 	CLICOLOR        - "0" disables colors
+//
+// ColorAuto is returned if none of them decides.
+func ColorModeFromEnv() ColorMode {
+	if CheckNoColor() {
+		return ColorNever
+	}
+
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		switch strings.ToLower(v) {
+		case "0", "false":
+			return ColorNever
+		}
+
+		return ColorAlways
+	}
+
+	if v, ok := os.LookupEnv("CLICOLOR_FORCE"); ok && v != "0" {
+		return ColorAlways
+	}
+
+	if os.Getenv("CLICOLOR") == "0" {
+		return ColorNever
+	}
+
+	return ColorAuto
+}
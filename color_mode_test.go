@@ -0,0 +1,65 @@
+package logftext
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestColorModeFromEnv(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Env      map[string]string
+		Expected ColorMode
+	}{
+		{"Empty", map[string]string{}, ColorAuto},
+		{"NoColor", map[string]string{"NO_COLOR": ""}, ColorNever},
+		{"NoColorWinsForceColor", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, ColorNever},
+		{"ForceColor", map[string]string{"FORCE_COLOR": ""}, ColorAlways},
+		{"ForceColorZero", map[string]string{"FORCE_COLOR": "0", "CLICOLOR_FORCE": "1"}, ColorNever},
+		{"CliColorForce", map[string]string{"CLICOLOR_FORCE": "1", "CLICOLOR": "0"}, ColorAlways},
+		{"CliColorForceZero", map[string]string{"CLICOLOR_FORCE": "0"}, ColorAuto},
+		{"CliColorZero", map[string]string{"CLICOLOR": "0"}, ColorNever},
+		{"CliColorOne", map[string]string{"CLICOLOR": "1"}, ColorAuto},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			defer setEnv([]string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR"}, tc.Env)()
+
+			require.Equal(t, tc.Expected, ColorModeFromEnv())
+		})
+	}
+}
+
+// setEnv sets the given environment variables that are present in the set
+// and unsets others. It returns a function that restores the previous state.
+func setEnv(keys []string, set map[string]string) func() {
+	type state struct {
+		value string
+		ok    bool
+	}
+	prev := make(map[string]state, len(keys))
+
+	for _, k := range keys {
+		v, ok := os.LookupEnv(k)
+		prev[k] = state{v, ok}
+
+		if v, ok := set[k]; ok {
+			os.Setenv(k, v)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+
+	return func() {
+		for k, s := range prev {
+			if s.ok {
+				os.Setenv(k, s.value)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package logftext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssgreg/logf"
+)
+
+// DecoderConfig allows to configure Decoder. It should match EncoderConfig
+// used to produce the logs.
+type DecoderConfig struct {
+	// TimeLayout specifies the layout of entry time. It's time.StampMilli
+	// by default, the same as the default Encoder time layout.
+	TimeLayout string
+
+	// DisableFieldName specifies that logs contain no logger names. Logger
+	// name is a first word of a message ending with a colon otherwise.
+	DisableFieldName bool
+}
+
+// WithDefaults returns the new config in which all uninitialized fields are
+// filled with their default values.
+func (c DecoderConfig) WithDefaults() DecoderConfig {
+	if c.TimeLayout == "" {
+		c.TimeLayout = time.StampMilli
+	}
+
+	return c
+}
+
+// Decoder parses lines produced by the text Encoder back to log entries.
+//
+// The text format is ambiguous in some cases, e.g. a message ending with
+// a "key=value" pair is parsed as a message with a field.
+type Decoder struct {
+	cfg DecoderConfig
+}
+
+// NewDecoder creates the new instance of Decoder with the given
+// DecoderConfig.
+func NewDecoder(cfg DecoderConfig) *Decoder {
+	return &Decoder{cfg.WithDefaults()}
+}
+
+// DecodedEntry is a log entry parsed by Decoder.
+type DecodedEntry struct {
+	logf.Entry
+
+	// LevelOK is false if the level badge is not a known one, e.g. "UNKN".
+	// Level holds a value that is encoded with the "UNKN" badge then.
+	LevelOK bool
+}
+
+// levelUnknown is the level of entries with unknown levels. Encoder
+// prints it with the "UNKN" badge.
+const levelUnknown logf.Level = -1
+
+// Decode parses a single log line with or without escape sequences.
+//
+// Field values are converted to logf fields as follows: JSON strings to
+// String, booleans to Bool, numbers to Any with json.Number, arrays and
+// objects to Any with json.RawMessage, null to Any with nil and unquoted
+// words to String.
+func (d *Decoder) Decode(line []byte) (DecodedEntry, error) {
+	var e DecodedEntry
+
+	line = bytes.TrimRight(StripEscapes(line), "\r\n")
+
+	// Time and level.
+	found := findLevel(line)
+	if found == -1 {
+		return e, errors.New("logftext: level is not found")
+	}
+
+	t, err := time.Parse(d.cfg.TimeLayout, string(line[:found]))
+	if err != nil {
+		return e, fmt.Errorf("logftext: failed to parse time: %v", err)
+	}
+	e.Time = t
+	e.Level, e.LevelOK = ParseLevel(string(line[found+2 : found+6]))
+
+	rest := line[found+7:]
+	if len(rest) != 0 {
+		// Skip a separator.
+		rest = rest[1:]
+	}
+
+	// Caller. It's either right after the level or at the end.
+	if caller, n := parseCaller(rest); n != 0 {
+		e.Caller = caller
+		rest = rest[n:]
+	} else {
+		rest, e.Caller = cutCaller(rest)
+	}
+
+	// Logger name. Spaces around names and messages are dropped since
+	// they can be the padding added by EncoderConfig.AlignFields.
+	rest = bytes.TrimLeft(rest, " ")
+	if !d.cfg.DisableFieldName {
+		end := bytes.IndexByte(rest, ' ')
+		if end == -1 {
+			end = len(rest)
+		}
+		if end > 1 && rest[end-1] == ':' && bytes.IndexByte(rest[:end], '=') == -1 {
+			e.LoggerName = string(rest[:end-1])
+			rest = bytes.TrimLeft(rest[end:], " ")
+		}
+	}
+
+	// Message and fields. Fields are the longest list of key=value pairs
+	// at the end of the line.
+	rest = bytes.TrimRight(rest, " ")
+	fields, start := parseFields(rest)
+	e.Text = string(bytes.TrimRight(rest[:start], " "))
+	e.Fields = fields
+
+	return e, nil
+}
+
+// StripEscapes returns a copy of the given text without CSI and OSC escape
+// sequences.
+func StripEscapes(text []byte) []byte {
+	r := make([]byte, 0, len(text))
+
+	for i := 0; i < len(text); {
+		if l := escapeLen(text[i:]); l != 0 {
+			i += l
+
+			continue
+		}
+		r = append(r, text[i])
+		i++
+	}
+
+	return r
+}
+
+// escapeLen returns the length of the CSI or OSC escape sequence at the
+// beginning of the given text or zero if the text doesn't start with an
+// escape sequence. An unterminated sequence takes the rest of the text.
+func escapeLen(text []byte) int {
+	if len(text) < 2 || text[0] != '\x1b' {
+		return 0
+	}
+
+	switch text[1] {
+	case '[':
+		// CSI sequence ends with a byte in range 0x40-0x7e.
+		for i := 2; i < len(text); i++ {
+			if text[i] >= 0x40 && text[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		// OSC sequence ends with BEL or ST.
+		for i := 2; i < len(text); i++ {
+			if text[i] == '\x07' {
+				return i + 1
+			}
+			if text[i] == '\x1b' && i+1 < len(text) && text[i+1] == '\\' {
+				return i + 2
+			}
+		}
+	default:
+		return 0
+	}
+
+	return len(text)
+}
+
+// findLevel returns the position of the separator followed by the level
+// badge like " |INFO|" or -1.
+func findLevel(line []byte) int {
+	for i := 0; i+7 <= len(line); i++ {
+		if line[i] == ' ' && line[i+1] == '|' && line[i+6] == '|' {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ParseLevel parses a level badge written by the text Encoder, e.g. "WARN",
+// or a level name known to logf.LevelFromString, e.g. "warning". For
+// unknown levels it returns false and a level the Encoder prints with the
+// "UNKN" badge.
+func ParseLevel(s string) (logf.Level, bool) {
+	switch s {
+	case "DEBU":
+		return logf.LevelDebug, true
+	case "WARN":
+		return logf.LevelWarn, true
+	case "ERRO":
+		return logf.LevelError, true
+	}
+	if lvl, ok := logf.LevelFromString(s); ok {
+		return lvl, true
+	}
+
+	return levelUnknown, false
+}
+
+// cutCaller cuts the caller in form of ` @file:line` or ` @"file:line"`
+// with an optional function name from the end of the given text.
+func cutCaller(text []byte) ([]byte, logf.EntryCaller) {
+	limit := len(text)
+	for {
+		found := bytes.LastIndexByte(text[:limit], '@')
+		if found == -1 {
+			return text, logf.EntryCaller{}
+		}
+		limit = found
+
+		if found != 0 && text[found-1] != ' ' {
+			continue
+		}
+		caller, n := parseCaller(text[found:])
+		if n == 0 || found+n != len(text) {
+			continue
+		}
+		if found != 0 {
+			found--
+		}
+
+		return text[:found], caller
+	}
+}
+
+// parseCaller parses the caller at the beginning of the given text in form
+// of `@file:line` or `@"file:line"` followed by an optional function name
+// in parentheses. It returns the caller and its length or zero if there
+// is no caller.
+func parseCaller(text []byte) (logf.EntryCaller, int) {
+	var caller logf.EntryCaller
+	if len(text) < 2 || text[0] != '@' {
+		return caller, 0
+	}
+
+	var location string
+	n := 0
+	if text[1] == '"' {
+		n = scanValue(text[1:])
+		if n == -1 || json.Unmarshal(text[1:1+n], &location) != nil {
+			return caller, 0
+		}
+		n++
+	} else {
+		n = bytes.IndexByte(text, ' ')
+		if n == -1 {
+			n = len(text)
+		}
+		location = string(text[1:n])
+	}
+	if n != len(text) && text[n] != ' ' {
+		return caller, 0
+	}
+
+	// Function name.
+	if bytes.HasPrefix(text[n:], []byte(" (")) {
+		end := bytes.IndexByte(text[n+1:], ' ')
+		if end == -1 {
+			end = len(text) - n - 1
+		}
+		if text[n+end] == ')' {
+			n += end + 1
+		}
+	}
+
+	sep := strings.LastIndexByte(location, ':')
+	if sep == -1 {
+		return caller, 0
+	}
+	line, err := strconv.Atoi(location[sep+1:])
+	if err != nil {
+		return caller, 0
+	}
+
+	caller.File = location[:sep]
+	caller.Line = line
+	caller.Specified = true
+
+	return caller, n
+}
+
+// parseFields parses space-separated key=value pairs at the end of the
+// given text. It scans the text once from right to left and returns the
+// fields with the position where the first of them starts.
+func parseFields(text []byte) ([]logf.Field, int) {
+	var fields []logf.Field
+
+	start := len(text)
+	unmatched := false
+	for end := len(text); end != 0; {
+		// Key and value are separated with the first equal sign of a word
+		// unless the value is a JSON string, array or object that can
+		// contain spaces. Once a value has no matching opening character
+		// the rest is scanned by words, otherwise each field could make
+		// the scan reach the beginning of the text.
+		vs := -1
+		if !unmatched {
+			vs, unmatched = scanValueBack(text[:end])
+		}
+		if vs < 1 || text[vs-1] != '=' {
+			vs = bytes.LastIndexByte(text[:end], ' ') + 1
+			eq := bytes.IndexByte(text[vs:end], '=')
+			if eq == -1 {
+				break
+			}
+			vs += eq + 1
+		}
+		ks := bytes.LastIndexByte(text[:vs-1], ' ') + 1
+		if ks == vs-1 || bytes.IndexByte(text[ks:vs-1], '=') != -1 || scanValue(text[vs:end]) != end-vs {
+			break
+		}
+
+		field, ok := ParseField(string(text[ks:vs-1]), text[vs:end])
+		if !ok {
+			break
+		}
+		fields = append(fields, field)
+		start = ks
+
+		end = ks
+		if end != 0 {
+			// Skip a separator.
+			end--
+		}
+	}
+
+	// Fields are collected in reverse order.
+	for i, j := 0, len(fields)-1; i < j; i, j = i+1, j-1 {
+		fields[i], fields[j] = fields[j], fields[i]
+	}
+
+	return fields, start
+}
+
+// scanValue returns the length of the value at the beginning of the given
+// text or -1 if the value is malformed. JSON strings, arrays and objects
+// can contain spaces, other values end with a space.
+func scanValue(text []byte) int {
+	if len(text) == 0 {
+		return 0
+	}
+
+	switch text[0] {
+	case '"', '[', '{':
+	default:
+		n := bytes.IndexByte(text, ' ')
+		if n == -1 {
+			n = len(text)
+		}
+
+		return n
+	}
+
+	depth := 0
+	inString := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inString:
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+
+		if !inString && depth == 0 {
+			return i + 1
+		}
+	}
+
+	return -1
+}
+
+// ParseField converts the given key and value to a logf field the same way
+// Decoder does, see Decode. The value is a JSON value or an unquoted word.
+// It returns false if the value is a malformed JSON string, array or
+// object.
+func ParseField(key string, v []byte) (logf.Field, bool) {
+	if len(v) == 0 {
+		return logf.String(key, ""), true
+	}
+
+	switch v[0] {
+	case '"':
+		var s string
+		if json.Unmarshal(v, &s) != nil {
+			return logf.Field{}, false
+		}
+
+		return logf.String(key, s), true
+	case '[', '{':
+		if !json.Valid(v) {
+			return logf.Field{}, false
+		}
+
+		return logf.Any(key, json.RawMessage(v)), true
+	}
+
+	switch string(v) {
+	case "true":
+		return logf.Bool(key, true), true
+	case "false":
+		return logf.Bool(key, false), true
+	case "null":
+		return logf.Any(key, nil), true
+	}
+
+	if (v[0] == '-' || (v[0] >= '0' && v[0] <= '9')) && json.Valid(v) {
+		// Any converts Stringers to strings, so the field is created
+		// directly to keep a number.
+		return logf.Field{Key: key, Type: logf.FieldTypeAny, Any: json.Number(v)}, true
+	}
+
+	return logf.String(key, string(v)), true
+}
+
+// scanValueBack returns the start of the JSON string, array or object at
+// the end of the given text or -1 if there is no such value. It also
+// reports whether the value has no matching opening character.
+func scanValueBack(text []byte) (int, bool) {
+	depth := 0
+	inString := false
+	for i := len(text) - 1; i >= 0; i-- {
+		c := text[i]
+		switch {
+		case c == '"':
+			if escaped(text[:i]) {
+				continue
+			}
+			inString = !inString
+		case inString:
+		case c == ']' || c == '}':
+			depth++
+		case c == '[' || c == '{':
+			depth--
+		}
+
+		switch {
+		case depth < 0:
+			return -1, false
+		case !inString && depth == 0:
+			if i == len(text)-1 && c != '"' {
+				// Not a JSON value.
+				return -1, false
+			}
+
+			return i, false
+		}
+	}
+
+	return -1, true
+}
+
+// escaped reports whether the byte following the given text is escaped
+// with a backslash.
+func escaped(text []byte) bool {
+	n := 0
+	for n < len(text) && text[len(text)-1-n] == '\\' {
+		n++
+	}
+
+	return n%2 == 1
+}
@@ -0,0 +1,173 @@
+package logftext
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderRoundTrip(t *testing.T) {
+	entries := []logf.Entry{
+		{
+			Level: logf.LevelInfo,
+			Text:  "message",
+		},
+		{
+			Level: logf.LevelDebug,
+		},
+		{
+			Level:      logf.LevelWarn,
+			Text:       "message with a=b inside",
+			LoggerName: "name",
+			Time:       time.Date(0, time.March, 4, 5, 6, 7, 8000000, time.UTC),
+			Fields: []logf.Field{
+				logf.String("s", "with spaces \" and quotes"),
+				logf.Bool("b", true),
+				logf.Int("i", -1),
+				logf.Float64("f", 1.5),
+				logf.Any("n", nil),
+				logf.Object("o", &user{"n n"}),
+				logf.Array("a", users{{"n1"}, {"n2"}}),
+				logf.NamedError("e", errors.New("failed")),
+				logf.Duration("d", time.Second),
+			},
+			DerivedFields: []logf.Field{
+				logf.ConstBytes("bytes", []byte("!")),
+			},
+			Caller: logf.EntryCaller{
+				File:      "/a/b/c/f.go",
+				Line:      6,
+				Specified: true,
+			},
+		},
+	}
+
+	// Types of errors and durations are lost while decoding, so they are
+	// styled as strings to get the same colors back.
+	theme := DarkTheme()
+	theme.Duration = theme.String
+	theme.Error = theme.String
+
+	decoder := NewDecoder(DecoderConfig{})
+	for _, colorMode := range []ColorMode{ColorNever, ColorAlways} {
+		enc := NewEncoder(EncoderConfig{ColorMode: colorMode, Theme: theme})
+
+		for _, e := range entries {
+			b := logf.NewBuffer()
+			require.NoError(t, enc.Encode(b, e))
+
+			decoded, err := decoder.Decode(b.Bytes())
+			require.NoError(t, err)
+
+			rb := logf.NewBuffer()
+			require.NoError(t, enc.Encode(rb, decoded.Entry))
+			require.Equal(t, b.String(), rb.String())
+		}
+	}
+}
+
+func TestDecoderRoundTripValueColors(t *testing.T) {
+	theme := &Theme{
+		String:   NewStyle(EscYellow),
+		Duration: NewStyle(EscCyan),
+		Error:    NewStyle(EscRed),
+	}
+	enc := NewEncoder(EncoderConfig{ColorMode: ColorAlways, Theme: theme})
+
+	b := logf.NewBuffer()
+	require.NoError(t, enc.Encode(b, logf.Entry{
+		Level: logf.LevelInfo,
+		Fields: []logf.Field{
+			logf.Duration("d", time.Second),
+			logf.NamedError("e", errors.New("failed")),
+		},
+	}))
+	require.Contains(t, b.String(), "d=\x1b[36m\"1s\"\x1b[0m")
+	require.Contains(t, b.String(), "e=\x1b[31m\"failed\"\x1b[0m")
+
+	decoded, err := NewDecoder(DecoderConfig{}).Decode(b.Bytes())
+	require.NoError(t, err)
+
+	// Decoded errors and durations are strings.
+	rb := logf.NewBuffer()
+	require.NoError(t, enc.Encode(rb, decoded.Entry))
+	require.Contains(t, rb.String(), "d=\x1b[33m\"1s\"\x1b[0m")
+	require.Contains(t, rb.String(), "e=\x1b[33m\"failed\"\x1b[0m")
+}
+
+func TestDecoder(t *testing.T) {
+	e, err := NewDecoder(DecoderConfig{}).Decode([]byte("\x1b[90mMar  4 05:06:07.008\x1b[0m |\x1b[93;7mWARN\x1b[0m| \x1b[90mname:\x1b[0m \x1b[97mmessage\x1b[0m \x1b[32mi\x1b[0m\x1b[90m=\x1b[0m1 s=\"2\" o={\"k\":[1]}\x1b[90m @c/f.go:6\x1b[0m\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, time.Date(0, time.March, 4, 5, 6, 7, 8000000, time.UTC), e.Time)
+	require.Equal(t, logf.LevelWarn, e.Level)
+	require.True(t, e.LevelOK)
+	require.Equal(t, "name", e.LoggerName)
+	require.Equal(t, "message", e.Text)
+	require.Equal(t, []logf.Field{
+		{Key: "i", Type: logf.FieldTypeAny, Any: json.Number("1")},
+		logf.String("s", "2"),
+		logf.Any("o", json.RawMessage(`{"k":[1]}`)),
+	}, e.Fields)
+	require.Equal(t, logf.EntryCaller{File: "c/f.go", Line: 6, Specified: true}, e.Caller)
+
+	e, err = NewDecoder(DecoderConfig{}).Decode([]byte(`Mar  4 05:06:07.008 |INFO| message with @ inside @"c/f 1.go:6"`))
+	require.NoError(t, err)
+	require.Equal(t, "message with @ inside", e.Text)
+	require.Equal(t, logf.EntryCaller{File: "c/f 1.go", Line: 6, Specified: true}, e.Caller)
+
+	e, err = NewDecoder(DecoderConfig{}).Decode([]byte(`Mar  4 05:06:07.008 |INFO| @c/f.go:6 (main.(*T).f)   name: message i=1`))
+	require.NoError(t, err)
+	require.Equal(t, "name", e.LoggerName)
+	require.Equal(t, "message", e.Text)
+	require.Equal(t, logf.EntryCaller{File: "c/f.go", Line: 6, Specified: true}, e.Caller)
+
+	e, err = NewDecoder(DecoderConfig{}).Decode([]byte(`Mar  4 05:06:07.008 |UNKN| a=b message s="x y=z" k=x"y" e= w=a=b`))
+	require.NoError(t, err)
+	require.False(t, e.LevelOK)
+	require.Equal(t, "a=b message", e.Text)
+	require.Equal(t, []logf.Field{
+		logf.String("s", "x y=z"),
+		logf.String("k", `x"y"`),
+		logf.String("e", ""),
+		logf.String("w", "a=b"),
+	}, e.Fields)
+
+	b := logf.NewBuffer()
+	require.NoError(t, NewEncoder(EncoderConfig{ColorMode: ColorNever}).Encode(b, e.Entry))
+	require.Equal(t, "Mar  4 05:06:07.008 |UNKN| a=b message s=\"x y=z\" k=\"x\\\"y\\\"\" e=\"\" w=\"a=b\"\n", b.String())
+
+	e, err = NewDecoder(DecoderConfig{}).Decode([]byte(`Mar  4 05:06:07.008 |INFO| message o={"a b":1} x=} y=}`))
+	require.NoError(t, err)
+	require.Equal(t, "message o={\"a b\":1}", e.Text)
+	require.Equal(t, []logf.Field{logf.String("x", "}"), logf.String("y", "}")}, e.Fields)
+
+	_, err = NewDecoder(DecoderConfig{}).Decode([]byte("not a log line"))
+	require.Error(t, err)
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, expected := range map[string]logf.Level{
+		"DEBU":    logf.LevelDebug,
+		"INFO":    logf.LevelInfo,
+		"WARN":    logf.LevelWarn,
+		"ERRO":    logf.LevelError,
+		"debug":   logf.LevelDebug,
+		"warning": logf.LevelWarn,
+	} {
+		lvl, ok := ParseLevel(s)
+		require.True(t, ok, s)
+		require.Equal(t, expected, lvl, s)
+	}
+
+	lvl, ok := ParseLevel("UNKN")
+	require.False(t, ok)
+
+	b := logf.NewBuffer()
+	require.NoError(t, NewEncoder(EncoderConfig{ColorMode: ColorNever}).Encode(b, logf.Entry{Level: lvl}))
+	require.Equal(t, "Jan  1 00:00:00.000 |UNKN| \n", b.String())
+}
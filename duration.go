@@ -0,0 +1,122 @@
+package logftext
+
+import (
+	"time"
+
+	"github.com/ssgreg/logf"
+)
+
+// appendDuration appends the given duration in the same form as
+// time.Duration.String does, e.g. "1h2m0.5s" or "1.5ms", but without
+// allocations.
+func appendDuration(buf *logf.Buffer, d time.Duration) {
+	var arr [32]byte
+	w := len(arr)
+
+	u := uint64(d)
+	neg := d < 0
+	if neg {
+		u = -u
+	}
+
+	if u < uint64(time.Second) {
+		// Special case: if duration is smaller than a second, use smaller
+		// units, like 1.2ms.
+		var prec int
+		w--
+		arr[w] = 's'
+		w--
+		switch {
+		case u == 0:
+			arr[w] = '0'
+			buf.AppendBytes(arr[w:])
+
+			return
+		case u < uint64(time.Microsecond):
+			prec = 0
+			arr[w] = 'n'
+		case u < uint64(time.Millisecond):
+			prec = 3
+			// U+00B5 'µ' micro sign is 0xC2 0xB5.
+			w--
+			copy(arr[w:], "µ")
+		default:
+			prec = 6
+			arr[w] = 'm'
+		}
+		w, u = formatFraction(arr[:w], u, prec)
+		w = formatInt(arr[:w], u)
+	} else {
+		w--
+		arr[w] = 's'
+		w, u = formatFraction(arr[:w], u, 9)
+
+		// u is now an integer number of seconds.
+		w = formatInt(arr[:w], u%60)
+		u /= 60
+
+		// u is now an integer number of minutes.
+		if u > 0 {
+			w--
+			arr[w] = 'm'
+			w = formatInt(arr[:w], u%60)
+			u /= 60
+
+			// u is now an integer number of hours.
+			if u > 0 {
+				w--
+				arr[w] = 'h'
+				w = formatInt(arr[:w], u)
+			}
+		}
+	}
+
+	if neg {
+		w--
+		arr[w] = '-'
+	}
+	buf.AppendBytes(arr[w:])
+}
+
+// formatFraction formats the fraction of v/10**prec (e.g. ".12345") into
+// the tail of buf omitting trailing zeros. It omits the decimal point
+// entirely if the fraction is zero. It returns the index where the output
+// begins and v/10**prec.
+func formatFraction(buf []byte, v uint64, prec int) (int, uint64) {
+	w := len(buf)
+	print := false
+	for i := 0; i < prec; i++ {
+		digit := v % 10
+		print = print || digit != 0
+		if print {
+			w--
+			buf[w] = byte(digit) + '0'
+		}
+		v /= 10
+	}
+	if print {
+		w--
+		buf[w] = '.'
+	}
+
+	return w, v
+}
+
+// formatInt formats v into the tail of buf. It returns the index where the
+// output begins.
+func formatInt(buf []byte, v uint64) int {
+	w := len(buf)
+	if v == 0 {
+		w--
+		buf[w] = '0'
+
+		return w
+	}
+	for v > 0 {
+		w--
+		buf[w] = byte(v%10) + '0'
+		v /= 10
+	}
+
+	return w
+}
@@ -0,0 +1,26 @@
+package logftext
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendDuration(t *testing.T) {
+	durations := []time.Duration{
+		0, 1, 999, time.Microsecond, 1500 * time.Nanosecond, time.Millisecond,
+		1500 * time.Microsecond, time.Second, 1500 * time.Millisecond,
+		time.Minute, 90 * time.Second, time.Hour, 25*time.Hour + 30*time.Minute + 100*time.Millisecond,
+		-1, -1500 * time.Microsecond, -time.Hour, math.MaxInt64, math.MinInt64,
+	}
+
+	b := logf.NewBuffer()
+	for _, d := range durations {
+		b.Reset()
+		appendDuration(b, d)
+		require.Equal(t, d.String(), b.String())
+	}
+}
@@ -1,6 +1,10 @@
 package logftext
 
 import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ssgreg/logf"
@@ -12,22 +16,63 @@ import (
 // It's a caller responsibility to handle colored output in a prover way.
 // The best choice here is to use NewAppender function instead of of a
 // creation of Encoder by Yourselves.
+//
+// The Encoder is safe for concurrent use, e.g. it can be shared between
+// several appenders.
 var NewEncoder = encoderGetter(
 	func(cfg EncoderConfig) logf.Encoder {
+		custom := cfg.EncodeTime != nil || cfg.EncodeDuration != nil || cfg.EncodeError != nil || cfg.FilterKey != nil
+		stringDurations := cfg.EncodeDuration == nil
 		cfg = cfg.WithDefaults()
 
-		return &encoder{
-			cfg,
-			logf.NewJSONTypeEncoderFactory(logf.JSONEncoderConfig{
-				EncodeTime:     cfg.EncodeTime,
-				EncodeDuration: cfg.EncodeDuration,
-				EncodeError:    cfg.EncodeError,
+		s := &sharedEncoder{
+			EncoderConfig: cfg,
+			pal: cfg.Theme.compile(EscapeSequence{
+				NoColor: cfg.ColorMode == ColorNever || cfg.Format == FormatLogfmt,
+				Level:   cfg.ColorLevel,
+				NoAttrs: AllAttrs &^ cfg.TermCaps.Attrs,
 			}),
-			nil,
-			logf.NewCache(100),
-			0,
-			EscapeSequence{*cfg.NoColor},
+			nameColumn:    column{fixed: cfg.NameWidth},
+			msgColumn:     column{fixed: cfg.MsgWidth},
+			formatObjects: cfg.Format == FormatText && (cfg.IndentObjects || cfg.ColorMode != ColorNever),
+			hyperlinks:    cfg.Format == FormatText && cfg.ColorMode != ColorNever && cfg.TermCaps.Hyperlinks,
+			linkParts:     parseLinkTemplate(cfg.CallerURL),
+
+			orderFields:     cfg.SortFields || len(cfg.FirstKeys) != 0 || len(cfg.LastKeys) != 0,
+			truncate:        cfg.Format == FormatText && cfg.Overflow == OverflowTruncate && cfg.Width > 0,
+			filterKeys:      cfg.FilterKey != nil || len(cfg.AllowKeys) != 0 || len(cfg.DenyKeys) != 0 || len(cfg.RedactKeys) != 0,
+			stringDurations: stringDurations,
+		}
+
+		cc := cacheConfig{
+			pal:           s.pal,
+			format:        cfg.Format,
+			unquote:       cfg.UnquoteStrings,
+			formatObjects: s.formatObjects,
+			indentObjects: cfg.IndentObjects,
+			expandErrors:  cfg.ExpandErrors,
+			expandStacks:  cfg.ExpandStacks,
+			orderFields:   s.orderFields,
+			allowKeys:     strings.Join(cfg.AllowKeys, "\x00"),
+			denyKeys:      strings.Join(cfg.DenyKeys, "\x00"),
+			redactKeys:    strings.Join(cfg.RedactKeys, "\x00"),
 		}
+		s.cacheID = cfg.Cache.configID(cc, custom)
+		cc.expanded = true
+		s.expandedCacheID = cfg.Cache.configID(cc, custom)
+		s.pool.New = func() interface{} {
+			return &encoder{
+				sharedEncoder: s,
+				mf: logf.NewJSONTypeEncoderFactory(logf.JSONEncoderConfig{
+					EncodeTime:     cfg.EncodeTime,
+					EncodeDuration: cfg.EncodeDuration,
+					EncodeError:    cfg.EncodeError,
+				}),
+				scratch: logf.NewBuffer(),
+			}
+		}
+
+		return s
 	},
 )
 
@@ -37,236 +82,554 @@ func (c encoderGetter) Default() logf.Encoder {
 	return c(EncoderConfig{})
 }
 
-type encoder struct {
+// sharedEncoder is the Encoder returned by NewEncoder. It holds the state
+// that is shared between entries and is safe for concurrent use. Each
+// call of Encode takes a separate encoder from the pool.
+type sharedEncoder struct {
 	EncoderConfig
+
+	pal palette
+
+	cacheID         uint32
+	expandedCacheID uint32
+
+	nameColumn column
+	msgColumn  column
+
+	formatObjects bool
+	hyperlinks    bool
+	linkParts     []linkPart
+	filterKeys    bool
+	orderFields   bool
+	truncate      bool
+
+	// stringDurations is set if durations are encoded with the default
+	// logf.StringDurationEncoder that is replaced with appendDuration to
+	// avoid allocations.
+	stringDurations bool
+
+	pool sync.Pool
+}
+
+func (s *sharedEncoder) Encode(buf *logf.Buffer, e logf.Entry) error {
+	f := s.pool.Get().(*encoder)
+	f.encode(buf, e)
+	f.release()
+	s.pool.Put(f)
+
+	return nil
+}
+
+// encoder holds the state of a single Encode call.
+type encoder struct {
+	*sharedEncoder
 	mf logf.TypeEncoderFactory
 
 	buf         *logf.Buffer
-	cache       *logf.Cache
 	startBufLen int
 
-	eseq EscapeSequence
+	valueStart int
+	valueSeq   StyleSeq
+	scratch    *logf.Buffer
+
+	msgEnd     int
+	paddingEnd int
+
+	lineWidth int
+	fullWidth int
+
+	expanded bool
+	inError  bool
+	details  []detail
+	order    []fieldRef
+	values   []valueSpan
+}
+
+// release drops references to the data of the encoded entry, so errors
+// and keys are not kept reachable by encoders in the pool. Details are
+// cleared up to the capacity since encodeExpanded can drop some of them
+// in the middle of a call.
+func (f *encoder) release() {
+	f.buf = nil
+
+	details := f.details[:cap(f.details)]
+	for i := range details {
+		details[i] = detail{}
+	}
+	f.details = f.details[:0]
+
+	for i := range f.order {
+		f.order[i] = fieldRef{}
+	}
+	f.order = f.order[:0]
 }
 
-func (f *encoder) Encode(buf *logf.Buffer, e logf.Entry) error {
-	// TODO: move to clone
+func (f *encoder) encode(buf *logf.Buffer, e logf.Entry) {
 	f.buf = buf
 	f.startBufLen = f.buf.Len()
 
-	// Time.
-	f.eseq.At(f.buf, EscBrightBlack, func() {
-		appendTime(e.Time, f.buf, f.EncodeTime, f.mf.TypeEncoder(buf))
-	})
+	if f.Format == FormatLogfmt {
+		f.encodeLogfmtHeader(e)
+		f.encodeFields(e, false)
+		f.encodeCaller(e)
+	} else {
+		f.details = f.details[:0]
+		f.encodeHeader(e)
+		f.encodeText(e)
+		f.encodeDetails()
+	}
 
-	// Level.
-	f.appendSeparator()
-	appendLevel(buf, f.eseq, e.Level)
+	buf.AppendByte('\n')
+}
 
-	// Logger name.
-	if !f.DisableFieldName && e.LoggerName != "" {
-		f.appendSeparator()
-		f.eseq.At(f.buf, EscBrightBlack, func() {
-			f.buf.AppendString(e.LoggerName)
-			f.buf.AppendByte(':')
-		})
+// encodeText encodes fields and the caller of the entry in the text
+// format after the header is encoded.
+func (f *encoder) encodeText(e logf.Entry) {
+	details := len(f.details)
+	f.values = f.values[:0]
+	if f.Layout == LayoutExpanded {
+		n := len(e.DerivedFields) + len(e.Fields)
+		if (f.ExpandFieldCount == 0 && f.ExpandLineWidth == 0) || (f.ExpandFieldCount != 0 && n >= f.ExpandFieldCount) {
+			f.encodeExpanded(e, details)
+
+			return
+		}
 	}
 
-	// Message.
-	f.appendSeparator()
-	f.eseq.At(f.buf, EscBrightWhite, func() {
-		f.buf.AppendString(e.Text)
-	})
+	wrap := f.Overflow == OverflowWrap && f.Width > 0
+	if wrap {
+		f.lineWidth = visibleWidth(f.buf.Data[f.startBufLen:])
+		f.fullWidth = f.lineWidth
+	}
+
+	f.encodeFields(e, wrap)
+
+	if f.CallerPosition != CallerAfterLevel {
+		start := f.buf.Len()
+		f.encodeCaller(e)
+		if wrap && f.buf.Len() != start {
+			f.wrap(start)
+		}
+	}
+
+	// Drop the message padding if nothing follows it.
+	if f.paddingEnd != 0 && f.buf.Len() == f.paddingEnd {
+		f.buf.Data = f.buf.Data[:f.msgEnd]
+	}
+
+	if f.Layout == LayoutExpanded && f.ExpandLineWidth != 0 {
+		width := f.fullWidth
+		if !wrap {
+			width = visibleWidth(f.buf.Data[f.startBufLen:])
+		}
+		if width > f.ExpandLineWidth {
+			f.encodeExpanded(e, details)
+
+			return
+		}
+	}
+
+	if f.truncate {
+		f.truncateValues()
+	}
+}
 
-	// Logger's fields.
-	if bytes, ok := f.cache.Get(e.LoggerID); ok {
+// encodeExpanded encodes the caller on the header line and each field on
+// its own indented line. Everything that follows the message and details
+// recorded after the given number of details are dropped first.
+func (f *encoder) encodeExpanded(e logf.Entry, details int) {
+	f.buf.Data = f.buf.Data[:f.msgEnd]
+	f.details = f.details[:details]
+	if f.CallerPosition != CallerAfterLevel {
+		f.encodeCaller(e)
+	}
+
+	f.expanded = true
+	f.encodeFields(e, false)
+	f.expanded = false
+}
+
+func (f *encoder) encodeFields(e logf.Entry, wrap bool) {
+	buf := f.buf
+
+	// Logger's fields. The cache is bypassed if lines are wrapped since
+	// line breaks depend on the preceding text and if values are truncated
+	// since their positions are needed. Fields with details that are
+	// printed after the line are not cached as well.
+	key := cacheKey{f.cacheID, e.LoggerID}
+	if f.expanded {
+		key.config = f.expandedCacheID
+	}
+	if f.orderFields {
+		f.encodeOrderedFields(e, wrap, key)
+
+		return
+	}
+	if wrap || f.truncate {
+		for _, field := range e.DerivedFields {
+			start := buf.Len()
+			f.acceptField(field)
+			if wrap {
+				f.wrap(start)
+			}
+		}
+	} else if bytes, _, ok := f.Cache.get(key); ok {
 		buf.AppendBytes(bytes)
 	} else {
 		le := buf.Len()
+		details := len(f.details)
 		for _, field := range e.DerivedFields {
-			field.Accept(f)
+			f.acceptField(field)
 		}
 
-		bf := make([]byte, buf.Len()-le)
-		copy(bf, buf.Data[le:])
-		f.cache.Set(e.LoggerID, bf)
+		if len(f.details) == details {
+			bf := make([]byte, buf.Len()-le)
+			copy(bf, buf.Data[le:])
+			f.Cache.set(key, bf, nil)
+		}
 	}
 
 	// Entry's fields.
 	for _, field := range e.Fields {
-		field.Accept(f)
+		start := buf.Len()
+		f.acceptField(field)
+		if wrap {
+			f.wrap(start)
+		}
 	}
+}
 
-	// Caller.
-	if !f.DisableFieldCaller && e.Caller.Specified {
-		f.eseq.At(f.buf, EscBrightBlack, func() {
+func (f *encoder) encodeCaller(e logf.Entry) {
+	if f.DisableFieldCaller || !e.Caller.Specified {
+		return
+	}
+
+	if f.Format == FormatLogfmt {
+		f.addKey(logfmtKeyCaller)
+		f.beginValue(f.pal.value)
+		f.EncodeCaller(e.Caller, f.mf.TypeEncoder(f.buf))
+		f.endValue()
+	} else {
+		link := f.hasCallerLink(e.Caller)
+
+		start := f.buf.Len()
+		f.pal.caller.Begin(f.buf)
+		f.appendSeparator()
+		if link {
+			f.beginCallerLink(f.buf, e.Caller)
+		}
+		f.appendCaller(e.Caller)
+		if link {
+			endLink(f.buf)
+		}
+		f.pal.caller.End(f.buf)
+
+		if f.CallerPosition == CallerRight {
+			f.alignCaller(start)
+		}
+	}
+}
+
+func (f *encoder) encodeHeader(e logf.Entry) {
+	buf := f.buf
+
+	// Time.
+	f.pal.time.Begin(buf)
+	appendTime(e.Time, f.buf, f.EncodeTime, f.mf.TypeEncoder(buf))
+	f.pal.time.End(buf)
+
+	// Level.
+	f.appendSeparator()
+	appendLevel(buf, f.pal.level(e.Level), e.Level)
+
+	// Caller. It's padded to the CallerWidth, the padding is added even if
+	// there is no caller to keep messages aligned.
+	if f.CallerPosition == CallerAfterLevel && !f.DisableFieldCaller {
+		start := buf.Len()
+		f.encodeCaller(e)
+		if buf.Len() == start && f.CallerWidth > 0 {
 			f.appendSeparator()
-			f.buf.AppendByte('@')
-			f.EncodeCaller(e.Caller, f.mf.TypeEncoder(f.buf))
-		})
+		}
+		if buf.Len() != start {
+			appendPadding(buf, f.CallerWidth-visibleWidth(buf.Data[start:])+1)
+		}
 	}
 
-	buf.AppendByte('\n')
+	// Logger name.
+	if !f.DisableFieldName {
+		n := 0
+		if e.LoggerName != "" {
+			n = stringWidth(e.LoggerName) + 1
+		}
+		width := n
+		if f.AlignFields {
+			width = f.nameColumn.fit(n)
+		}
 
-	return nil
+		if width != 0 {
+			f.appendSeparator()
+			if n != 0 {
+				f.pal.name.Begin(buf)
+				f.buf.AppendString(e.LoggerName)
+				f.buf.AppendByte(':')
+				f.pal.name.End(buf)
+			}
+			appendPadding(buf, width-n)
+		}
+	}
+
+	// Message.
+	f.appendSeparator()
+	f.pal.msg.AppendString(buf, e.Text)
+
+	f.msgEnd = buf.Len()
+	f.paddingEnd = 0
+	if f.AlignFields {
+		n := stringWidth(e.Text)
+		appendPadding(buf, f.msgColumn.fit(n)-n)
+		f.paddingEnd = buf.Len()
+	}
 }
 
 func (f *encoder) EncodeFieldAny(k string, v interface{}) {
 	f.addKey(k)
+	f.beginValue(f.anyStyle(v))
 	f.mf.TypeEncoder(f.buf).EncodeTypeAny(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldBool(k string, v bool) {
 	f.addKey(k)
+	f.beginValue(f.pal.bool)
 	f.mf.TypeEncoder(f.buf).EncodeTypeBool(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInt64(k string, v int64) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInt64(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInt32(k string, v int32) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInt32(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInt16(k string, v int16) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInt16(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInt8(k string, v int8) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInt8(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUint64(k string, v uint64) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUint64(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUint32(k string, v uint32) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUint32(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUint16(k string, v uint16) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUint16(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUint8(k string, v uint8) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUint8(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldFloat64(k string, v float64) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeFloat64(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldFloat32(k string, v float32) {
 	f.addKey(k)
+	f.beginValue(f.pal.number)
 	f.mf.TypeEncoder(f.buf).EncodeTypeFloat32(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldString(k string, v string) {
+	if f.ExpandStacks && f.Format == FormatText && !f.inError && isStack(v) {
+		f.details = append(f.details, detail{key: k, stack: v})
+
+		return
+	}
+
 	f.addKey(k)
+	f.beginValue(f.stringStyle())
 	f.mf.TypeEncoder(f.buf).EncodeTypeString(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldDuration(k string, v time.Duration) {
 	f.addKey(k)
-	f.mf.TypeEncoder(f.buf).EncodeTypeDuration(v)
+	f.beginValue(f.pal.duration)
+	if f.stringDurations {
+		f.buf.AppendByte('"')
+		appendDuration(f.buf, v)
+		f.buf.AppendByte('"')
+	} else {
+		f.mf.TypeEncoder(f.buf).EncodeTypeDuration(v)
+	}
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldError(k string, v error) {
+	if f.ExpandErrors && f.Format == FormatText {
+		f.encodeExpandedError(k, v)
+
+		return
+	}
+
+	f.inError = true
 	f.EncodeError(k, v, f)
+	f.inError = false
 }
 
 func (f *encoder) EncodeFieldTime(k string, v time.Time) {
 	f.addKey(k)
+	f.beginValue(f.pal.timestamp)
 	f.mf.TypeEncoder(f.buf).EncodeTypeTime(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldArray(k string, v logf.ArrayEncoder) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeArray(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldObject(k string, v logf.ObjectEncoder) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeObject(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldBytes(k string, v []byte) {
 	f.addKey(k)
+	f.beginValue(f.pal.str)
 	f.mf.TypeEncoder(f.buf).EncodeTypeBytes(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldBools(k string, v []bool) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeBools(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldStrings(k string, v []string) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeStrings(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInts64(k string, v []int64) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInts64(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInts32(k string, v []int32) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInts32(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInts16(k string, v []int16) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInts16(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldInts8(k string, v []int8) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeInts8(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUints64(k string, v []uint64) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUints64(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUints32(k string, v []uint32) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUints32(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUints16(k string, v []uint16) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUints16(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldUints8(k string, v []uint8) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeUints8(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldFloats64(k string, v []float64) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeFloats64(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldFloats32(k string, v []float32) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeFloats32(v)
+	f.endValue()
 }
 
 func (f *encoder) EncodeFieldDurations(k string, v []time.Duration) {
 	f.addKey(k)
+	f.beginValue(f.pal.value)
 	f.mf.TypeEncoder(f.buf).EncodeTypeDurations(v)
+	f.endValue()
 }
 
 func (f *encoder) appendSeparator() {
@@ -286,45 +649,174 @@ func (f *encoder) empty() bool {
 }
 
 func (f *encoder) addKey(k string) {
-	f.appendSeparator()
-	f.eseq.At(f.buf, EscGreen, func() {
-		f.buf.AppendString(k)
-	})
+	if f.expanded {
+		f.buf.AppendByte('\n')
+		f.buf.AppendString(indent)
+		f.pal.key.AppendString(f.buf, k)
+		f.pal.equal.AppendByte(f.buf, ':')
+		f.buf.AppendByte(' ')
 
-	f.eseq.At(f.buf, EscBrightBlack, func() {
+		return
+	}
+
+	f.appendSeparator()
+	if f.Format == FormatLogfmt {
+		appendLogfmtKey(f.buf, k)
 		f.buf.AppendByte('=')
-	})
+
+		return
+	}
+
+	f.pal.key.AppendString(f.buf, k)
+	f.pal.equal.AppendByte(f.buf, '=')
+}
+
+// beginValue must be called before a field value is encoded. The given
+// StyleSeq is applied to the whole value.
+func (f *encoder) beginValue(seq StyleSeq) {
+	f.valueSeq = seq
+	f.valueSeq.Begin(f.buf)
+	f.valueStart = f.buf.Len()
+}
+
+// endValue must be called after a field value is encoded.
+func (f *encoder) endValue() {
+	if f.filterKeys && f.buf.Len() != f.valueStart {
+		switch f.buf.Data[f.valueStart] {
+		case '{', '[':
+			f.filterNested(f.valueStart)
+		}
+	}
+
+	if f.Format == FormatLogfmt {
+		f.quoteLogfmtValue(f.valueStart)
+	} else if f.buf.Len() != f.valueStart {
+		v := f.buf.Data[f.valueStart:]
+		switch v[0] {
+		case '{', '[':
+			if f.formatObjects {
+				f.formatNested(f.valueStart)
+			}
+		case '"':
+			// Strings that look like other JSON values are kept quoted,
+			// otherwise they are parsed back as numbers, booleans, nils,
+			// objects or arrays.
+			if f.UnquoteStrings && len(v) > 2 && !needsQuoting(v[1:len(v)-1]) && !looksLikeJSON(v[1:len(v)-1]) {
+				f.unquote(f.valueStart)
+			}
+		}
+	}
+	if f.truncate && !f.expanded {
+		f.values = append(f.values, valueSpan{start: f.valueStart, end: f.buf.Len()})
+	}
+	f.valueSeq.End(f.buf)
+}
+
+// looksLikeJSON reports whether the given string content is a JSON value
+// other than a string.
+func looksLikeJSON(s []byte) bool {
+	switch s[0] {
+	case '{', '[':
+		return true
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return json.Valid(s)
+	}
+
+	switch string(s) {
+	case "true", "false", "null":
+		return true
+	}
+
+	return false
+}
+
+// stringStyle returns the style of a string value. Strings encoded by
+// ErrorEncoder are errors.
+func (f *encoder) stringStyle() StyleSeq {
+	if f.inError {
+		return f.pal.error
+	}
+
+	return f.pal.str
+}
+
+// anyStyle returns the style of a value of unknown type.
+func (f *encoder) anyStyle(v interface{}) StyleSeq {
+	switch v.(type) {
+	case nil:
+		return f.pal.nil
+	case bool:
+		return f.pal.bool
+	case string:
+		return f.pal.str
+	case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8, float64, float32, json.Number:
+		return f.pal.number
+	case time.Duration:
+		return f.pal.duration
+	case time.Time:
+		return f.pal.timestamp
+	case error:
+		return f.pal.error
+	}
+
+	return f.pal.value
 }
 
-func appendLevel(buf *logf.Buffer, eseq EscapeSequence, lvl logf.Level) {
+func appendLevel(buf *logf.Buffer, seq StyleSeq, lvl logf.Level) {
 	buf.AppendByte('|')
+	seq.Begin(buf)
 
 	switch lvl {
 	case logf.LevelDebug:
-		eseq.At(buf, EscMagenta, func() {
-			buf.AppendString("DEBU")
-		})
+		buf.AppendString("DEBU")
 	case logf.LevelInfo:
-		eseq.At(buf, EscCyan, func() {
-			buf.AppendString("INFO")
-		})
+		buf.AppendString("INFO")
 	case logf.LevelWarn:
-		eseq.At2(buf, EscBrightYellow, EscReverse, func() {
-			buf.AppendString("WARN")
-		})
+		buf.AppendString("WARN")
 	case logf.LevelError:
-		eseq.At2(buf, EscBrightRed, EscReverse, func() {
-			buf.AppendString("ERRO")
-		})
+		buf.AppendString("ERRO")
 	default:
-		eseq.At(buf, EscBrightRed, func() {
-			buf.AppendString("UNKN")
-		})
+		buf.AppendString("UNKN")
 	}
 
+	seq.End(buf)
 	buf.AppendByte('|')
 }
 
+// MaxLearnedWidth is the maximum width of a column learned by Encoder if
+// AlignFields is set. Longer values do not affect the column width.
+const MaxLearnedWidth = 40
+
+// column is the width of a logger name or a message column. It's either
+// fixed or learned from the values seen so far. It's safe for concurrent
+// use.
+type column struct {
+	fixed   int
+	learned int32
+}
+
+// fit returns the width of the column for a value of the given width.
+func (c *column) fit(n int) int {
+	if c.fixed != 0 {
+		return c.fixed
+	}
+	for {
+		learned := atomic.LoadInt32(&c.learned)
+		if n <= int(learned) || n > MaxLearnedWidth {
+			return int(learned)
+		}
+		if atomic.CompareAndSwapInt32(&c.learned, learned, int32(n)) {
+			return n
+		}
+	}
+}
+
+func appendPadding(buf *logf.Buffer, n int) {
+	for ; n > 0; n-- {
+		buf.AppendByte(' ')
+	}
+}
+
 func appendTime(t time.Time, buf *logf.Buffer, enc logf.TimeEncoder, encType logf.TypeEncoder) {
 	start := buf.Len()
 	enc(t, encType)
@@ -333,7 +825,7 @@ func appendTime(t time.Time, buf *logf.Buffer, enc logf.TimeEncoder, encType log
 	// Get rid of possible quotes.
 	if end != start {
 		if buf.Data[start] == '"' && buf.Back() == '"' {
-			copy(buf.Data[start:end], buf.Data[start+1:end-2])
+			copy(buf.Data[start:end], buf.Data[start+1:end-1])
 			buf.Data = buf.Data[0 : end-2]
 		}
 	}
@@ -8,8 +8,130 @@ import (
 
 // EncoderConfig allows to configure text Encoder.
 type EncoderConfig struct {
-	// NoColor enables/disables colored output.
-	NoColor *bool
+	// ColorMode enables/disables colored output.
+	ColorMode ColorMode
+
+	// Format specifies the output format. Colors are never used with
+	// FormatLogfmt.
+	Format Format
+
+	// ColorLevel specifies the maximum color level supported by a terminal.
+	// Extended colors are downgraded to match it. TermCaps.Colors is used
+	// if no ColorLevel is specified.
+	ColorLevel ColorLevel
+
+	// TermCaps specifies capabilities of a terminal. Unsupported colors and
+	// text attributes are dropped. All colors and text attributes but no
+	// hyperlinks are supposed to be supported if no TermCaps is specified.
+	TermCaps *TermCaps
+
+	// Theme specifies colors of log entry elements. DefaultTheme is used
+	// if no Theme is specified.
+	Theme *Theme
+
+	// AlignFields enables padding of logger names and messages so that
+	// fields of consecutive entries start in the same column. It's ignored
+	// with FormatLogfmt.
+	AlignFields bool
+
+	// NameWidth and MsgWidth specify widths logger names (including a
+	// trailing colon) and messages are padded to if AlignFields is set.
+	// A width that is not specified is learned from the entries encoded
+	// so far, up to MaxLearnedWidth.
+	NameWidth int
+	MsgWidth  int
+
+	// Layout specifies the placement of fields. It's ignored with
+	// FormatLogfmt.
+	Layout Layout
+
+	// ExpandFieldCount and ExpandLineWidth make LayoutExpanded apply only
+	// to entries with at least ExpandFieldCount fields or to entries wider
+	// than ExpandLineWidth in LayoutLine. LayoutExpanded applies to all
+	// entries if neither is specified.
+	ExpandFieldCount int
+	ExpandLineWidth  int
+
+	// UnquoteStrings enables printing of string values without quotes if
+	// they contain no spaces, quotes, equal signs or control characters
+	// and don't look like numbers, booleans, nulls, objects or arrays.
+	// It's ignored with FormatLogfmt that always quotes strings only if
+	// needed.
+	UnquoteStrings bool
+
+	// ExpandErrors enables printing of error causes and verbose messages
+	// (e.g. stack traces) on separate lines after the entry. Errors are
+	// encoded with EncodeError otherwise. It's ignored with FormatLogfmt.
+	ExpandErrors bool
+
+	// ExpandStacks enables printing of string values that look like Go
+	// stack traces on separate lines after the entry, one frame per line.
+	// Runtime and testing frames are dropped, file paths are shortened. It's
+	// ignored with FormatLogfmt.
+	ExpandStacks bool
+
+	// IndentObjects enables the indented multi-line form of objects and
+	// arrays. Their lines are indented like wrapped lines. It's ignored
+	// with FormatLogfmt.
+	IndentObjects bool
+
+	// Overflow specifies how to handle lines wider than Width. It's
+	// ignored with FormatLogfmt and for entries in LayoutExpanded.
+	Overflow Overflow
+
+	// Width specifies the maximum width of a line. NewAppender sets it to
+	// the terminal width if Overflow or CallerRight is specified.
+	Width int
+
+	// CallerPosition specifies where the caller is placed. It's ignored
+	// with FormatLogfmt.
+	CallerPosition CallerPosition
+
+	// CallerWidth specifies the width the caller is padded to if it's
+	// placed after the level.
+	CallerWidth int
+
+	// CallerFunction enables printing of the caller function name after
+	// the location. It's ignored with FormatLogfmt.
+	CallerFunction bool
+
+	// CallerURL specifies the URL template of the caller hyperlink, e.g.
+	// "vscode://file{path}:{line}". Placeholders {path} and {line} are
+	// replaced with the caller file path and line. The path is usually
+	// absolute, so it already starts with a slash. A file URL is used if no
+	// CallerURL is specified and the path is absolute. Hyperlinks are
+	// printed only if colors are enabled and TermCaps.Hyperlinks is set.
+	CallerURL string
+
+	// SortFields enables sorting of fields by key. Logger's and entry's
+	// fields are sorted together.
+	SortFields bool
+
+	// FirstKeys and LastKeys specify keys or glob patterns of fields that
+	// are printed right after the message and at the end respectively, in
+	// order of patterns. Other fields are printed in between.
+	FirstKeys []string
+	LastKeys  []string
+
+	// AllowKeys, DenyKeys and RedactKeys specify keys or glob patterns in
+	// path.Match syntax to filter fields by. Fields with keys not matching
+	// AllowKeys (if any) or matching DenyKeys are dropped, values of fields
+	// with keys matching RedactKeys are replaced with "***". DenyKeys and
+	// RedactKeys also apply to keys inside objects.
+	AllowKeys  []string
+	DenyKeys   []string
+	RedactKeys []string
+
+	// FilterKey specifies the function returning the Action for each field
+	// key, including keys inside objects. It's combined with AllowKeys,
+	// DenyKeys and RedactKeys, the strongest Action wins.
+	FilterKey func(key string) Action
+
+	// Cache specifies the cache of encoded logger's fields. It can be
+	// shared between Encoders, but Encoders with custom EncodeTime,
+	// EncodeDuration, EncodeError or FilterKey never share cached fields.
+	// A Cache of DefaultCacheSize is created if no Cache is specified.
+	Cache *Cache
 
 	DisableFieldName   bool
 	DisableFieldCaller bool
@@ -23,9 +145,20 @@ type EncoderConfig struct {
 // WithDefaults returns the new config in which all uninitialized fields are
 // filled with their default values.
 func (c EncoderConfig) WithDefaults() EncoderConfig {
-	if c.NoColor == nil {
-		noColor := false
-		c.NoColor = &noColor
+	if c.ColorMode == ColorAuto {
+		c.ColorMode = ColorAlways
+	}
+	if c.TermCaps == nil {
+		c.TermCaps = &TermCaps{ColorLevelTrueColor, AllAttrs, false}
+	}
+	if c.ColorLevel == ColorLevelAuto {
+		c.ColorLevel = c.TermCaps.Colors
+	}
+	if c.Theme == nil {
+		c.Theme = DefaultTheme()
+	}
+	if c.Cache == nil {
+		c.Cache = NewCache(DefaultCacheSize)
 	}
 
 	// Handle defaults for type encoder.
@@ -33,7 +166,11 @@ func (c EncoderConfig) WithDefaults() EncoderConfig {
 		c.EncodeDuration = logf.StringDurationEncoder
 	}
 	if c.EncodeTime == nil {
-		c.EncodeTime = logf.LayoutTimeEncoder(time.StampMilli)
+		if c.Format == FormatLogfmt {
+			c.EncodeTime = logf.RFC3339NanoTimeEncoder
+		} else {
+			c.EncodeTime = logf.LayoutTimeEncoder(time.StampMilli)
+		}
 	}
 	if c.EncodeError == nil {
 		c.EncodeError = logf.DefaultErrorEncoder
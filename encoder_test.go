@@ -2,7 +2,12 @@ package logftext
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"math/rand"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -43,6 +48,31 @@ func (u users) EncodeLogfArray(enc logf.TypeEncoder) error {
 	return nil
 }
 
+type joinedError []error
+
+func (e joinedError) Error() string {
+	return "joined"
+}
+
+func (e joinedError) Unwrap() []error {
+	return e
+}
+
+type verboseError struct{}
+
+func (e verboseError) Error() string {
+	return "verbose"
+}
+
+func (e verboseError) Format(s fmt.State, verb rune) {
+	if s.Flag('+') {
+		io.WriteString(s, "verbose\nmain.f\n\t/a/b/c/f.go:6\n")
+
+		return
+	}
+	io.WriteString(s, e.Error())
+}
+
 func TestEncoder(t *testing.T) {
 	testCases := []encoderTestCase{
 		{
@@ -67,7 +97,7 @@ func TestEncoder(t *testing.T) {
 					Text:     "another message",
 				},
 			},
-			`0001-01-01T00:00:000 |WARN| another message` + "\n",
+			`0001-01-01T00:00:00Z |WARN| another message` + "\n",
 			true,
 			EncoderConfig{
 				EncodeTime: logf.RFC3339TimeEncoder,
@@ -130,7 +160,7 @@ func TestEncoder(t *testing.T) {
 					},
 				},
 			},
-			`Jan  1 00:00:00.000 |WARN| message drs=["1s"] fts=[0.1,9] @"c/f.go:6"` + "\n",
+			`Jan  1 00:00:00.000 |WARN| message drs=["1s"] fts=[0.1,9] @c/f.go:6` + "\n",
 			true,
 			EncoderConfig{},
 		},
@@ -231,7 +261,7 @@ func TestEncoder(t *testing.T) {
 					},
 				},
 			},
-			`Jan  1 00:00:00.000 |WARN| name: message test="f" @"c/f.go:6"` + "\n",
+			`Jan  1 00:00:00.000 |WARN| name: message test="f" @c/f.go:6` + "\n",
 			true,
 			EncoderConfig{},
 		},
@@ -254,25 +284,1036 @@ func TestEncoder(t *testing.T) {
 					},
 				},
 			},
-			"\x1b[90mJan  1 00:00:00.000\x1b[0m |\x1b[93;7mWARN\x1b[0m| \x1b[90mname:\x1b[0m \x1b[97mmessage\x1b[0m \x1b[32mtest\x1b[0m\x1b[90m=\x1b[0m\"f\"\x1b[90m @\"c/f.go:6\"\x1b[0m" + "\n",
+			"\x1b[90mJan  1 00:00:00.000\x1b[0m |\x1b[93;7mWARN\x1b[0m| \x1b[90mname:\x1b[0m \x1b[97mmessage\x1b[0m \x1b[32mtest\x1b[0m\x1b[90m=\x1b[0m\x1b[33m\"f\"\x1b[0m\x1b[90m @c/f.go:6\x1b[0m" + "\n",
 			false,
 			EncoderConfig{},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.Name, func(t *testing.T) {
-			b := logf.NewBuffer()
-
-			for _, e := range tc.Entry {
-				cfg := tc.Config
-				cfg.NoColor = &tc.NoColor
-
-				enc := NewEncoder(cfg)
-				enc.Encode(b, e)
-			}
-
-			require.EqualValues(t, tc.Golden, b.String())
-		})
-	}
+		{
+			"WithLightTheme",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.Int("i", 1),
+					},
+				},
+			},
+			"\x1b[90mJan  1 00:00:00.000\x1b[0m |\x1b[34mINFO\x1b[0m| \x1b[30mmessage\x1b[0m \x1b[34mi\x1b[0m\x1b[90m=\x1b[0m\x1b[36m1\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Theme: LightTheme(),
+			},
+		},
+		{
+			"WithCustomTheme",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelError,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.Int("i", 1),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |\x1b[1;31mERRO\x1b[0m| message i\x1b[2m=\x1b[0m\x1b[4m1\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Theme: &Theme{
+					Equal:      []EscapeCode{EscFaint},
+					Value:      []EscapeCode{EscUnderline},
+					LevelError: []EscapeCode{EscBold, EscRed},
+				},
+			},
+		},
+		{
+			"WithExtendedColors",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+				},
+			},
+			"Jan  1 00:00:00.000 |\x1b[38;5;33;48;2;0;0;0mINFO\x1b[0m| \x1b[38;2;255;128;0mmessage\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Theme: &Theme{
+					Msg:       []EscapeCode{RGB(255, 128, 0)},
+					LevelInfo: []EscapeCode{Color256(33), BgRGB(0, 0, 0)},
+				},
+			},
+		},
+		{
+			"WithDowngradedColors",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+				},
+			},
+			"Jan  1 00:00:00.000 |\x1b[94;40mINFO\x1b[0m| \x1b[33mmessage\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Theme: &Theme{
+					Msg:       []EscapeCode{RGB(255, 128, 0)},
+					LevelInfo: []EscapeCode{Color256(12), BgColor256(16)},
+				},
+				ColorLevel: ColorLevel16,
+			},
+		},
+		{
+			"WithDowngradedTrueColors",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+				},
+			},
+			"Jan  1 00:00:00.000 |\x1b[38;5;12;48;5;232mINFO\x1b[0m| \x1b[38;5;208mmessage\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Theme: &Theme{
+					Msg:       []EscapeCode{RGB(255, 128, 0)},
+					LevelInfo: []EscapeCode{Color256(12), BgRGB(10, 10, 10)},
+				},
+				ColorLevel: ColorLevel256,
+			},
+		},
+		{
+			"WithColorLevelNone",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelError,
+					Text:     "message",
+				},
+			},
+			"Jan  1 00:00:00.000 |\x1b[7mERRO\x1b[0m| message" + "\n",
+			false,
+			EncoderConfig{
+				ColorLevel: ColorLevelNone,
+			},
+		},
+		{
+			"WithUnsupportedAttrs",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelError,
+					Text:     "message",
+				},
+			},
+			"Jan  1 00:00:00.000 |\x1b[7mERRO\x1b[0m| \x1b[4mmessage\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Theme: &Theme{
+					Time:       NewStyle().Italic(),
+					Msg:        NewStyle().Italic().Underline(),
+					LevelError: NewStyle(EscBrightRed).Reverse(),
+				},
+				TermCaps: &TermCaps{ColorLevelNone, AttrUnderline | AttrReverse, false},
+			},
+		},
+		{
+			"WithWrappedFields",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.String("first", "value"),
+						logf.String("second", "value"),
+						logf.String("third", "value"),
+					},
+					DerivedFields: []logf.Field{
+						logf.Int("derived", 1),
+					},
+					Caller: logf.EntryCaller{
+						PC:        0,
+						File:      "/a/b/c/f.go",
+						Line:      6,
+						Specified: true,
+					},
+				},
+			},
+			"\x1b[90mJan  1 00:00:00.000\x1b[0m |\x1b[36mINFO\x1b[0m| \x1b[97mmessage\x1b[0m \x1b[32mderived\x1b[0m\x1b[90m=\x1b[0m\x1b[94m1\x1b[0m\n    \x1b[32mfirst\x1b[0m\x1b[90m=\x1b[0m\x1b[33m\"value\"\x1b[0m \x1b[32msecond\x1b[0m\x1b[90m=\x1b[0m\x1b[33m\"value\"\x1b[0m \x1b[32mthird\x1b[0m\x1b[90m=\x1b[0m\x1b[33m\"value\"\x1b[0m\x1b[90m\n    @c/f.go:6\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Overflow: OverflowWrap,
+				Width:    50,
+			},
+		},
+		{
+			"WithTruncatedLine",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.String("a", "long value one"),
+						logf.String("b", "x"),
+						logf.Int("n", 123456789),
+						logf.String("w", "日本語テキスト"),
+					},
+				},
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.String("a", "fits"),
+					},
+				},
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "a message that is already wider than the line itself",
+					Fields: []logf.Field{
+						logf.Int("status", 200),
+						logf.String("q", `sayso "hi" twice`),
+					},
+				},
+			},
+			`Jan  1 00:00:00.000 |INFO| message a="long va…" b="x" n=123456789 w="日本語…"` + "\n" +
+				`Jan  1 00:00:00.000 |INFO| message a="fits"` + "\n" +
+				`Jan  1 00:00:00.000 |INFO| a message that is already wider than the line itself status=200 q="sayso …"` + "\n",
+			true,
+			EncoderConfig{
+				Overflow: OverflowTruncate,
+				Width:    70,
+			},
+		},
+		{
+			"WithTruncatedColoredValues",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.Any("o", map[string]string{"key": "value"}),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| message \x1b[32mo\x1b[0m={\x1b[32m\"key\"\x1b[0m:\x1b[33m\"v…\x1b[0m\n",
+			false,
+			EncoderConfig{
+				Overflow: OverflowTruncate,
+				Width:    40,
+				Theme:    &Theme{Key: NewStyle(EscGreen), String: NewStyle(EscYellow)},
+			},
+		},
+		{
+			"WithExpandedLayout",
+			[]logf.Entry{
+				{
+					LoggerID:   int32(rand.Int()),
+					Level:      logf.LevelInfo,
+					Text:       "message",
+					LoggerName: "name",
+					Fields: []logf.Field{
+						logf.String("s", "value"),
+						logf.Object("o", &user{"n"}),
+					},
+					DerivedFields: []logf.Field{
+						logf.Int("derived", 1),
+					},
+					Caller: logf.EntryCaller{
+						PC:        0,
+						File:      "/a/b/c/f.go",
+						Line:      6,
+						Specified: true,
+					},
+				},
+			},
+			"\x1b[90mJan  1 00:00:00.000\x1b[0m |\x1b[36mINFO\x1b[0m| \x1b[90mname:\x1b[0m \x1b[97mmessage\x1b[0m\x1b[90m @c/f.go:6\x1b[0m\n" +
+				"    \x1b[32mderived\x1b[0m\x1b[90m:\x1b[0m \x1b[94m1\x1b[0m\n" +
+				"    \x1b[32ms\x1b[0m\x1b[90m:\x1b[0m \x1b[33m\"value\"\x1b[0m\n" +
+				"    \x1b[32mo\x1b[0m\x1b[90m:\x1b[0m {\x1b[32m\"name\"\x1b[0m:\x1b[33m\"n\"\x1b[0m}\n",
+			false,
+			EncoderConfig{
+				Layout: LayoutExpanded,
+			},
+		},
+		{
+			"WithExpandedLayoutThresholds",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "short",
+					Fields: []logf.Field{
+						logf.Int("a", 1),
+					},
+				},
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "many fields",
+					Fields: []logf.Field{
+						logf.Int("a", 1),
+						logf.Int("b", 2),
+						logf.Int("c", 3),
+					},
+				},
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "long line",
+					Fields: []logf.Field{
+						logf.String("a", "very long value"),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| short a=1\n" +
+				"Jan  1 00:00:00.000 |INFO| many fields\n    a: 1\n    b: 2\n    c: 3\n" +
+				"Jan  1 00:00:00.000 |INFO| long line\n    a: \"very long value\"\n",
+			true,
+			EncoderConfig{
+				Layout:           LayoutExpanded,
+				ExpandFieldCount: 3,
+				ExpandLineWidth:  50,
+			},
+		},
+		{
+			"WithExpandedLineWidthAndErrors",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelError,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.Error(fmt.Errorf("wrap: %w", errors.New("root"))),
+						logf.String("stack", "goroutine 1 [running]:\nmain.f()\n\t/a/b/c/f.go:6 +0x1d\n"),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |ERRO| message\n" +
+				"    error: \"wrap: root\"\n" +
+				"    error: wrap: root\n" +
+				"      caused by: root\n" +
+				"    stack:\n" +
+				"      goroutine 1 [running]:\n" +
+				"      /a/b/c/f.go:6 main.f\n",
+			true,
+			EncoderConfig{
+				Layout:          LayoutExpanded,
+				ExpandLineWidth: 40,
+				ExpandErrors:    true,
+				ExpandStacks:    true,
+			},
+		},
+		{
+			"WithColoredObjects",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.Any("m", map[string]interface{}{"a": []interface{}{1.5, true, nil, "s"}}),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| message \x1b[32mm\x1b[0m={\x1b[32m\"a\"\x1b[0m:[\x1b[34m1.5\x1b[0m,\x1b[35mtrue\x1b[0m,\x1b[90mnull\x1b[0m,\x1b[33m\"s\"\x1b[0m]}" + "\n",
+			false,
+			EncoderConfig{
+				Theme: &Theme{
+					Key:    NewStyle(EscGreen),
+					String: NewStyle(EscYellow),
+					Number: NewStyle(EscBlue),
+					Bool:   NewStyle(EscMagenta),
+					Nil:    NewStyle(EscBrightBlack),
+				},
+			},
+		},
+		{
+			"WithIndentedObjects",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.Array("a", users{{"n1"}, {"n2"}}),
+						logf.Any("e", map[string]interface{}{"o": map[string]interface{}{}, "a": []int{}}),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| message a=[\n      {\n        \"name\": \"n1\"\n      },\n      {\n        \"name\": \"n2\"\n      }\n    ] e={\n      \"a\": [],\n      \"o\": {}\n    }" + "\n",
+			true,
+			EncoderConfig{
+				IndentObjects: true,
+			},
+		},
+		{
+			"WithTypedValues",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.String("s", "v"),
+						logf.Int("i", 1),
+						logf.Bool("b", true),
+						logf.Any("n", nil),
+						logf.Duration("d", time.Second),
+						logf.Time("t", time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)),
+						logf.Error(errors.New("failed")),
+						logf.Any("v", struct{}{}),
+					},
+				},
+			},
+			"0001-01-01T00:00:00Z |INFO| message s=\x1b[33m\"v\"\x1b[0m i=\x1b[34m1\x1b[0m b=\x1b[35mtrue\x1b[0m n=\x1b[90mnull\x1b[0m d=\x1b[36m\"1s\"\x1b[0m t=\x1b[32m\"2020-01-02T03:04:05Z\"\x1b[0m error=\x1b[31m\"failed\"\x1b[0m v=\x1b[1m{}\x1b[0m" + "\n",
+			false,
+			EncoderConfig{
+				Theme: &Theme{
+					Value:     NewStyle().Bold(),
+					String:    NewStyle(EscYellow),
+					Number:    NewStyle(EscBlue),
+					Bool:      NewStyle(EscMagenta),
+					Nil:       NewStyle(EscBrightBlack),
+					Duration:  NewStyle(EscCyan),
+					Timestamp: NewStyle(EscGreen),
+					Error:     NewStyle(EscRed),
+				},
+				EncodeTime: logf.RFC3339TimeEncoder,
+			},
+		},
+		{
+			"WithUnquotedStrings",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.String("s", "simple"),
+						logf.String("n", "3"),
+						logf.String("v", "v1.2"),
+						logf.String("b", "true"),
+						logf.String("nil", "null"),
+						logf.String("sp", "with space"),
+						logf.String("eq", "a=b"),
+						logf.String("q", `"`),
+						logf.String("nl", "\n"),
+						logf.String("empty", ""),
+						logf.String("o", "{}"),
+						logf.Duration("d", time.Second),
+						logf.Error(errors.New("failed")),
+					},
+				},
+			},
+			`Jan  1 00:00:00.000 |INFO| message s=simple n="3" v=v1.2 b="true" nil="null" sp="with space" eq="a=b" q="\"" nl="\n" empty="" o="{}" d=1s error=failed` + "\n",
+			true,
+			EncoderConfig{
+				UnquoteStrings: true,
+			},
+		},
+		{
+			"WithExpandedErrors",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelError,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.Error(fmt.Errorf("read: %w", fmt.Errorf("open: %w", errors.New("no file")))),
+						logf.NamedError("j", joinedError{errors.New("a"), fmt.Errorf("b: %w", errors.New("c"))}),
+						logf.NamedError("v", verboseError{}),
+						logf.NamedError("s", errors.New("simple")),
+						logf.NamedError("n", joinedError{errors.New("a"), nil, errors.New("b")}),
+						logf.NamedError("m", joinedError{errors.New("a"), nil}),
+						logf.NamedError("e", joinedError{}),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |ERRO| message error=\"read: open: no file\" j=\"joined\" v=\"verbose\" s=\"simple\" n=\"joined\" m=\"joined\" e=\"joined\"\n" +
+				"    error: read: open: no file\n" +
+				"      caused by: open: no file\n" +
+				"      caused by: no file\n" +
+				"    j: joined\n" +
+				"      caused by: a\n" +
+				"      caused by: b: c\n" +
+				"        caused by: c\n" +
+				"    v: verbose\n" +
+				"      /a/b/c/f.go:6 main.f\n" +
+				"    n: joined\n" +
+				"      caused by: a\n" +
+				"      caused by: b\n" +
+				"    m: joined\n" +
+				"      caused by: a\n",
+			true,
+			EncoderConfig{
+				ExpandErrors: true,
+			},
+		},
+		{
+			"WithExpandedStack",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelError,
+					Text:     "message",
+					Fields: []logf.Field{
+						logf.String("stack", "goroutine 1 [running]:\n"+
+							"runtime/debug.Stack(0x1, 0x2)\n\t"+runtime.GOROOT()+"/src/runtime/debug/stack.go:24 +0x65\n"+
+							"net/http.(*conn).serve(0xc000010000)\n\t"+runtime.GOROOT()+"/src/net/http/server.go:100 +0x1d\n"+
+							"main.main()\n\t/a/b/c/f.go:6 +0x25\n"+
+							"testing.tRunner(0xc000010000, 0x1)\n\t"+runtime.GOROOT()+"/src/testing/testing.go:1 +0x1\n"),
+						logf.String("s", "not\n\ta stack"),
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |ERRO| message s=\"not\\n\\ta stack\"\n" +
+				"    stack:\n" +
+				"      goroutine 1 [running]:\n" +
+				"      net/http/server.go:100 net/http.(*conn).serve\n" +
+				"      /a/b/c/f.go:6 main.main\n",
+			true,
+			EncoderConfig{
+				ExpandStacks: true,
+			},
+		},
+		{
+			"WithCallerHyperlink",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Caller: logf.EntryCaller{
+						PC:        0,
+						File:      "/a/b/c/f 1.go",
+						Line:      6,
+						Specified: true,
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| message \x1b]8;;file:///a/b/c/f%201.go\x1b\\@\"c/f 1.go:6\"\x1b]8;;\x1b\\" + "\n",
+			false,
+			EncoderConfig{
+				Theme:    &Theme{},
+				TermCaps: &TermCaps{ColorLevelTrueColor, AllAttrs, true},
+			},
+		},
+		{
+			"WithCallerURL",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Caller: logf.EntryCaller{
+						PC:        0,
+						File:      "/a/b/c/f.go",
+						Line:      6,
+						Specified: true,
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| message \x1b]8;;vscode://file/a/b/c/f.go:6\x1b\\@c/f.go:6\x1b]8;;\x1b\\" + "\n",
+			false,
+			EncoderConfig{
+				Theme:     &Theme{},
+				TermCaps:  &TermCaps{ColorLevelTrueColor, AllAttrs, true},
+				CallerURL: "vscode://file{path}:{line}",
+			},
+		},
+		{
+			"WithCallerAfterLevel",
+			[]logf.Entry{
+				{
+					LoggerID:   int32(rand.Int()),
+					Level:      logf.LevelInfo,
+					Text:       "message",
+					LoggerName: "name",
+					Fields: []logf.Field{
+						logf.Int("i", 1),
+					},
+					Caller: logf.EntryCaller{
+						PC:        0,
+						File:      "/a/b/c/f.go",
+						Line:      6,
+						Specified: true,
+					},
+				},
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| @c/f.go:6    name: message i=1\n" +
+				"Jan  1 00:00:00.000 |INFO|              message\n",
+			true,
+			EncoderConfig{
+				CallerPosition: CallerAfterLevel,
+				CallerWidth:    12,
+			},
+		},
+		{
+			"WithCallerRight",
+			[]logf.Entry{
+				{
+					LoggerID: int32(rand.Int()),
+					Level:    logf.LevelInfo,
+					Text:     "message",
+					Caller: logf.EntryCaller{
+						PC:        0,
+						File:      "/a/b/c/f.go",
+						Line:      6,
+						Specified: true,
+					},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| message       @c/f.go:6\n",
+			true,
+			EncoderConfig{
+				CallerPosition: CallerRight,
+				Width:          50,
+			},
+		},
+		{
+			"WithFilteredKeys",
+			[]logf.Entry{
+				{
+					LoggerID:      int32(rand.Int()),
+					Level:         logf.LevelInfo,
+					Text:          "message",
+					DerivedFields: []logf.Field{logf.String("api_token", "t"), logf.Int("pid", 1)},
+					Fields: []logf.Field{
+						logf.String("password", "p"),
+						logf.Any("user", map[string]interface{}{
+							"name":     "a",
+							"password": "p",
+							"keys":     []map[string]string{{"api_token": "t", "id": "1"}},
+						}),
+						logf.Any("last", map[string]string{"a": "1", "z_token": "t"}),
+						logf.Any("only", map[string]string{"x_token": "t"}),
+					},
+				},
+			},
+			`Jan  1 00:00:00.000 |INFO| message pid=1 password="***" user={"keys":[{"id":"1"}],"name":"a","password":"***"} last={"a":"1"} only={}` + "\n",
+			true,
+			EncoderConfig{
+				DenyKeys:   []string{"*_token"},
+				RedactKeys: []string{"password"},
+			},
+		},
+		{
+			"WithAllowedKeys",
+			[]logf.Entry{
+				{
+					LoggerID:      int32(rand.Int()),
+					Level:         logf.LevelInfo,
+					Text:          "message",
+					DerivedFields: []logf.Field{logf.Int("pid", 1)},
+					Fields: []logf.Field{
+						logf.String("method", "GET"),
+						logf.String("secret", "s"),
+						logf.Any("req", map[string]string{"path": "/", "secret": "s"}),
+					},
+				},
+			},
+			`Jan  1 00:00:00.000 |INFO| message method="GET" req={"path":"/","secret":"***"}` + "\n",
+			true,
+			EncoderConfig{
+				AllowKeys: []string{"method", "req"},
+				FilterKey: func(key string) Action {
+					if key == "secret" {
+						return ActionRedact
+					}
+
+					return ActionKeep
+				},
+			},
+		},
+		{
+			"WithSortedFields",
+			[]logf.Entry{
+				{
+					LoggerID:      1,
+					Level:         logf.LevelInfo,
+					Text:          "message",
+					DerivedFields: []logf.Field{logf.Int("c", 3), logf.Int("a", 1)},
+					Fields:        []logf.Field{logf.Int("d", 4), logf.Int("b", 2)},
+				},
+				{
+					LoggerID:      1,
+					Level:         logf.LevelInfo,
+					Text:          "message",
+					DerivedFields: []logf.Field{logf.Int("c", 3), logf.Int("a", 1)},
+					Fields:        []logf.Field{logf.Int("b", 2)},
+				},
+			},
+			"Jan  1 00:00:00.000 |INFO| message a=1 b=2 c=3 d=4\n" +
+				"Jan  1 00:00:00.000 |INFO| message a=1 b=2 c=3\n",
+			true,
+			EncoderConfig{
+				SortFields: true,
+				Cache:      NewCache(10),
+			},
+		},
+		{
+			"WithPinnedKeys",
+			[]logf.Entry{
+				{
+					LoggerID:      2,
+					Level:         logf.LevelInfo,
+					Text:          "message",
+					DerivedFields: []logf.Field{logf.String("user", "u"), logf.Int("pid", 1)},
+					Fields:        []logf.Field{logf.Int("z", 1), logf.String("debug_a", "a"), logf.String("request_id", "r"), logf.Int("y", 2)},
+				},
+				{
+					LoggerID:      2,
+					Level:         logf.LevelInfo,
+					Text:          "message",
+					DerivedFields: []logf.Field{logf.String("user", "u"), logf.Int("pid", 1)},
+					Fields:        []logf.Field{logf.Int("x", 1)},
+				},
+			},
+			`Jan  1 00:00:00.000 |INFO| message request_id="r" user="u" pid=1 z=1 y=2 debug_a="a"` + "\n" +
+				`Jan  1 00:00:00.000 |INFO| message user="u" pid=1 x=1` + "\n",
+			true,
+			EncoderConfig{
+				FirstKeys: []string{"request_id", "user"},
+				LastKeys:  []string{"debug_*"},
+				Cache:     NewCache(10),
+			},
+		},
+		{
+			"Logfmt",
+			[]logf.Entry{
+				{
+					LoggerID:   int32(rand.Int()),
+					Level:      logf.LevelInfo,
+					Text:       "message with spaces",
+					LoggerName: "main",
+					Fields: []logf.Field{
+						logf.String("s", "simple"),
+						logf.String("q", `a "b"`),
+						logf.String("eq", "a=b"),
+						logf.String("empty", ""),
+						logf.String("bad key", "1"),
+						logf.Int("i", 1),
+						logf.Duration("d", time.Second),
+						logf.Object("o", &user{"n"}),
+						logf.ConstInts("is", []int{0, 1}),
+					},
+					DerivedFields: []logf.Field{
+						logf.ConstBytes("bytes", []byte("!")),
+					},
+					Caller: logf.EntryCaller{
+						PC:        0,
+						File:      "/a/b/c/f.go",
+						Line:      6,
+						Specified: true,
+					},
+				},
+			},
+			`time=0001-01-01T00:00:00Z level=info logger=main msg="message with spaces" bytes="IQ==" s=simple q="a \"b\"" eq="a=b" empty="" bad_key=1 i=1 d=1s o="{\"name\":\"n\"}" is="[0,1]" caller=c/f.go:6` + "\n",
+			false,
+			EncoderConfig{
+				Format: FormatLogfmt,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			b := logf.NewBuffer()
+
+			for _, e := range tc.Entry {
+				cfg := tc.Config
+				if tc.NoColor {
+					cfg.ColorMode = ColorNever
+				}
+
+				enc := NewEncoder(cfg)
+				enc.Encode(b, e)
+			}
+
+			require.EqualValues(t, tc.Golden, b.String())
+		})
+	}
+}
+
+func TestEncoderAlignFields(t *testing.T) {
+	entries := []logf.Entry{
+		{
+			Level:      logf.LevelInfo,
+			Text:       "started",
+			LoggerName: "main",
+			Fields:     []logf.Field{logf.Int("a", 1)},
+		},
+		{
+			Level:      logf.LevelInfo,
+			Text:       "request",
+			LoggerName: "http",
+			Fields:     []logf.Field{logf.Int("b", 2)},
+		},
+		{
+			Level:  logf.LevelInfo,
+			Text:   "ok",
+			Fields: []logf.Field{logf.Int("c", 3)},
+		},
+		{
+			Level:      logf.LevelInfo,
+			Text:       "done",
+			LoggerName: "main",
+		},
+	}
+
+	testCases := []struct {
+		Name   string
+		Golden string
+		Config EncoderConfig
+	}{
+		{
+			"Learned",
+			"Jan  1 00:00:00.000 |INFO| main: started a=1\n" +
+				"Jan  1 00:00:00.000 |INFO| http: request b=2\n" +
+				"Jan  1 00:00:00.000 |INFO|       ok      c=3\n" +
+				"Jan  1 00:00:00.000 |INFO| main: done\n",
+			EncoderConfig{},
+		},
+		{
+			"Fixed",
+			"Jan  1 00:00:00.000 |INFO| main:   started    a=1\n" +
+				"Jan  1 00:00:00.000 |INFO| http:   request    b=2\n" +
+				"Jan  1 00:00:00.000 |INFO|         ok         c=3\n" +
+				"Jan  1 00:00:00.000 |INFO| main:   done\n",
+			EncoderConfig{NameWidth: 7, MsgWidth: 10},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cfg := tc.Config
+			cfg.ColorMode = ColorNever
+			cfg.AlignFields = true
+			enc := NewEncoder(cfg)
+
+			b := logf.NewBuffer()
+			decoder := NewDecoder(DecoderConfig{})
+			for _, e := range entries {
+				start := b.Len()
+				require.NoError(t, enc.Encode(b, e))
+
+				decoded, err := decoder.Decode(b.Data[start:])
+				require.NoError(t, err)
+				require.Equal(t, e.LoggerName, decoded.LoggerName)
+				require.Equal(t, e.Text, decoded.Text)
+			}
+
+			require.EqualValues(t, tc.Golden, b.String())
+		})
+	}
+}
+
+func TestEncoderConcurrent(t *testing.T) {
+	entries := []logf.Entry{
+		{
+			LoggerID:      1,
+			Level:         logf.LevelInfo,
+			Text:          "started",
+			LoggerName:    "main",
+			DerivedFields: []logf.Field{logf.String("version", "1.0.0")},
+			Fields:        []logf.Field{logf.Int("a", 1), logf.Any("obj", map[string]int{"b": 2})},
+		},
+		{
+			LoggerID:      2,
+			Level:         logf.LevelError,
+			Text:          "failed",
+			LoggerName:    "http",
+			DerivedFields: []logf.Field{logf.Int("port", 80)},
+			Fields:        []logf.Field{logf.Error(errors.New("oops"))},
+		},
+	}
+
+	for _, cfg := range []EncoderConfig{
+		{},
+		{AlignFields: true, NameWidth: 5, MsgWidth: 8},
+		{Layout: LayoutExpanded, ExpandErrors: true},
+		{Format: FormatLogfmt},
+	} {
+		enc := NewEncoder(cfg)
+
+		golden := logf.NewBuffer()
+		for _, e := range entries {
+			require.NoError(t, enc.Encode(golden, e))
+		}
+
+		var wg sync.WaitGroup
+		results := make([]string, 8)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				for n := 0; n < 100; n++ {
+					b := logf.NewBuffer()
+					for _, e := range entries {
+						_ = enc.Encode(b, e)
+					}
+					results[i] = b.String()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for _, r := range results {
+			require.Equal(t, golden.String(), r)
+		}
+	}
+}
+
+func TestEncoderRelease(t *testing.T) {
+	s := NewEncoder(EncoderConfig{
+		ColorMode:       ColorNever,
+		ExpandErrors:    true,
+		SortFields:      true,
+		Layout:          LayoutExpanded,
+		ExpandLineWidth: 10,
+	}).(*sharedEncoder)
+	f := s.pool.New().(*encoder)
+
+	f.encode(logf.NewBuffer(), logf.Entry{
+		Level: logf.LevelError,
+		Fields: []logf.Field{
+			logf.Error(fmt.Errorf("wrap: %w", errors.New("root"))),
+			logf.String("key", "value"),
+		},
+	})
+	require.NotEmpty(t, f.details)
+	require.NotEmpty(t, f.order)
+
+	f.release()
+	require.Nil(t, f.buf)
+	require.Empty(t, f.details)
+	for _, d := range f.details[:cap(f.details)] {
+		require.Equal(t, detail{}, d)
+	}
+	for _, ref := range f.order[:cap(f.order)] {
+		require.Equal(t, fieldRef{}, ref)
+	}
+}
+
+func TestEncoderCallerFunction(t *testing.T) {
+	pc, file, line, _ := runtime.Caller(0)
+	e := logf.Entry{
+		Level:  logf.LevelInfo,
+		Text:   "message",
+		Caller: logf.EntryCaller{PC: pc, File: file, Line: line, Specified: true},
+	}
+
+	b := logf.NewBuffer()
+	require.NoError(t, NewEncoder(EncoderConfig{ColorMode: ColorNever, CallerFunction: true}).Encode(b, e))
+	require.Equal(t, fmt.Sprintf("Jan  1 00:00:00.000 |INFO| message @%s/encoder_test.go:%d (logftext.TestEncoderCallerFunction)\n", filepath.Base(filepath.Dir(file)), line), b.String())
+
+	decoded, err := NewDecoder(DecoderConfig{}).Decode(b.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, "message", decoded.Text)
+	require.Equal(t, line, decoded.Caller.Line)
+}
+
+func benchmarkEntry() logf.Entry {
+	return logf.Entry{
+		LoggerID:      1,
+		LoggerName:    "main",
+		Level:         logf.LevelInfo,
+		Time:          time.Date(2018, 11, 9, 15, 4, 5, 123000000, time.UTC),
+		Text:          "got request",
+		DerivedFields: []logf.Field{logf.String("service", "api"), logf.Int("pid", 42)},
+		Fields: []logf.Field{
+			logf.String("method", "GET"),
+			logf.String("path", "/api/v1/users"),
+			logf.Int("status", 200),
+			logf.Duration("elapsed", 1500*time.Microsecond),
+			logf.Bool("cached", true),
+		},
+		Caller: logf.EntryCaller{File: "/a/b/c/f.go", Line: 6, Specified: true},
+	}
+}
+
+// raceEnabled is set if tests are run with the race detector that makes
+// sync.Pool drop items randomly.
+var raceEnabled bool
+
+func TestEncoderAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not accurate with the race detector")
+	}
+
+	for _, cfg := range []EncoderConfig{
+		{ColorMode: ColorNever},
+		{ColorMode: ColorAlways, AlignFields: true},
+		{ColorMode: ColorAlways, Layout: LayoutExpanded},
+		{Format: FormatLogfmt},
+		{ColorMode: ColorAlways, SortFields: true, FirstKeys: []string{"path"}},
+		{ColorMode: ColorNever, CallerPosition: CallerRight, Width: 200},
+		{ColorMode: ColorAlways, TermCaps: &TermCaps{ColorLevel16, AllAttrs, true}},
+		{ColorMode: ColorAlways, TermCaps: &TermCaps{ColorLevel16, AllAttrs, true}, CallerURL: "vscode://file{path}:{line}"},
+	} {
+		enc := NewEncoder(cfg)
+		e := benchmarkEntry()
+		buf := logf.NewBufferWithCapacity(1024)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			buf.Reset()
+			_ = enc.Encode(buf, e)
+		})
+		require.Zero(t, allocs)
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	benchmarks := []struct {
+		Name    string
+		Encoder logf.Encoder
+	}{
+		{"JSON", logf.NewJSONEncoder.Default()},
+		{"Text", NewEncoder(EncoderConfig{ColorMode: ColorNever})},
+		{"TextColored", NewEncoder(EncoderConfig{ColorMode: ColorAlways})},
+		{"TextAligned", NewEncoder(EncoderConfig{ColorMode: ColorAlways, AlignFields: true})},
+		{"TextExpanded", NewEncoder(EncoderConfig{ColorMode: ColorAlways, Layout: LayoutExpanded})},
+		{"Logfmt", NewEncoder(EncoderConfig{Format: FormatLogfmt})},
+		{"TextSorted", NewEncoder(EncoderConfig{ColorMode: ColorAlways, SortFields: true, FirstKeys: []string{"path"}})},
+	}
+
+	e := benchmarkEntry()
+	for _, bm := range benchmarks {
+		b.Run(bm.Name, func(b *testing.B) {
+			buf := logf.NewBufferWithCapacity(1024)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				_ = bm.Encoder.Encode(buf, e)
+			}
+		})
+	}
+}
+
+func BenchmarkEncoderParallel(b *testing.B) {
+	enc := NewEncoder(EncoderConfig{ColorMode: ColorAlways})
+	e := benchmarkEntry()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		buf := logf.NewBufferWithCapacity(1024)
+		for pb.Next() {
+			buf.Reset()
+			_ = enc.Encode(buf, e)
+		}
+	})
 }
@@ -0,0 +1,167 @@
+package logftext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxErrorCauses limits the number of causes printed for a single error.
+const maxErrorCauses = 64
+
+// detail is an error or a stack trace field printed after the line.
+type detail struct {
+	key   string
+	err   error
+	stack string
+}
+
+// encodeExpandedError encodes the error message as a string field and
+// saves the error to print its details later.
+func (f *encoder) encodeExpandedError(k string, v error) {
+	msg := "<nil>"
+	if v != nil {
+		msg = v.Error()
+	}
+
+	f.inError = true
+	f.EncodeFieldString(k, msg)
+	f.inError = false
+
+	if v != nil {
+		f.details = append(f.details, detail{key: k, err: v})
+	}
+}
+
+// encodeDetails prints the saved stack traces and errors on separate
+// lines. Causes of an error are printed as follows:
+//
+// 	error: read config: open a.yml: no such file
+// 	  caused by: open a.yml: no such file
+// 	  caused by: no such file
+//
+// The verbose message is printed instead of causes if the error formats
+// itself differently with "%+v", e.g. it has a stack trace.
+func (f *encoder) encodeDetails() {
+	for _, d := range f.details {
+		if d.err == nil {
+			f.appendDetailLine(d.key, 0, nil)
+			f.appendStack(d.stack)
+
+			continue
+		}
+
+		msg := d.err.Error()
+		verbose := msg
+		if _, ok := d.err.(fmt.Formatter); ok {
+			verbose = fmt.Sprintf("%+v", d.err)
+		}
+
+		if verbose != msg {
+			lines := strings.SplitN(verbose, "\n", 2)
+			f.appendErrorLine(d.key, 0, lines[0])
+			if len(lines) > 1 {
+				f.appendStack(lines[1])
+			}
+
+			continue
+		}
+
+		if len(unwrapErrors(d.err)) == 0 {
+			continue
+		}
+		f.appendErrorLine(d.key, 0, msg)
+		f.appendCauses(d.err, 1, maxErrorCauses)
+	}
+}
+
+// appendCauses appends causes of the given error. A chain of single
+// causes is printed at the same depth, each of multiple causes starts
+// a deeper level.
+func (f *encoder) appendCauses(err error, depth int, limit int) int {
+	for limit > 0 {
+		causes := unwrapErrors(err)
+		switch len(causes) {
+		case 0:
+			return limit
+		case 1:
+			err = causes[0]
+			f.appendErrorLine("", depth, "caused by: "+err.Error())
+			limit--
+		default:
+			for _, cause := range causes {
+				if limit == 0 {
+					break
+				}
+				f.appendErrorLine("", depth, "caused by: "+cause.Error())
+				limit = f.appendCauses(cause, depth+1, limit-1)
+			}
+
+			return limit
+		}
+	}
+
+	return limit
+}
+
+func (f *encoder) appendErrorLine(key string, depth int, text string) {
+	f.appendDetailLine(key, depth, func() {
+		f.pal.error.AppendString(f.buf, text)
+	})
+}
+
+// appendDetailLine starts a new indented line with the given key and
+// calls the given fn to append the rest of the line.
+func (f *encoder) appendDetailLine(key string, depth int, fn func()) {
+	f.buf.AppendByte('\n')
+	f.buf.AppendString(indent)
+	for i := 0; i < depth; i++ {
+		f.buf.AppendString("  ")
+	}
+	if key != "" {
+		f.pal.key.AppendString(f.buf, key)
+		f.pal.equal.AppendByte(f.buf, ':')
+		if fn != nil {
+			f.buf.AppendByte(' ')
+		}
+	}
+	if fn != nil {
+		fn()
+	}
+}
+
+// unwrapErrors returns the errors wrapped by the given one using either
+// Unwrap() error or Unwrap() []error method. Nil errors are skipped.
+func unwrapErrors(err error) []error {
+	switch x := err.(type) {
+	case interface{ Unwrap() []error }:
+		return skipNilErrors(x.Unwrap())
+	case interface{ Unwrap() error }:
+		if cause := x.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	}
+
+	return nil
+}
+
+// skipNilErrors returns the given errors without nil ones. The slice is
+// copied only if it contains nil errors.
+func skipNilErrors(errs []error) []error {
+	for i, err := range errs {
+		if err != nil {
+			continue
+		}
+
+		r := make([]error, i, len(errs)-1)
+		copy(r, errs[:i])
+		for _, err := range errs[i+1:] {
+			if err != nil {
+				r = append(r, err)
+			}
+		}
+
+		return r
+	}
+
+	return errs
+}
@@ -14,7 +14,10 @@ import (
 // character " [ " (5Bh). The character or characters following the escape
 // and left-bracket characters specify an alphanumeric code that controls
 // a keyboard or display function.
-type EscapeCode int8
+//
+// Besides the basic codes, EscapeCode can hold an extended 256-color or
+// 24-bit color. Use Color256, BgColor256, RGB and BgRGB to create them.
+type EscapeCode int32
 
 // Text colors.
 const (
@@ -81,58 +84,36 @@ const (
 // EscapeSequence allows to construct escape sequences.
 type EscapeSequence struct {
 	NoColor bool
+
+	// Level specifies the maximum supported color level. Extended colors
+	// are downgraded to match it. ColorLevelAuto means no downgrading.
+	Level ColorLevel
+
+	// NoAttrs specifies text attributes that are not supported and must be
+	// dropped.
+	NoAttrs Attrs
 }
 
 // At calls the given fn, wrapped with the escape sequence,
 // based on the given code.
+//
+// Deprecated: Use Compile with a Style instead.
 func (es EscapeSequence) At(buf *logf.Buffer, clr EscapeCode, fn func()) {
-	if es.NoColor {
-		fn()
-
-		return
-	}
-
-	buf.AppendString("\x1b[")
-	logf.AppendInt(buf, int64(clr))
-	buf.AppendByte('m')
-	fn()
-	buf.AppendString("\x1b[0m")
+	es.Compile(NewStyle(clr)).At(buf, fn)
 }
 
 // At2 calls the given fn, wrapped with the escape sequence,
 // based on the given codes.
+//
+// Deprecated: Use Compile with a Style instead.
 func (es EscapeSequence) At2(buf *logf.Buffer, clr1, clr2 EscapeCode, fn func()) {
-	if es.NoColor {
-		fn()
-
-		return
-	}
-
-	buf.AppendString("\x1b[")
-	logf.AppendInt(buf, int64(clr1))
-	buf.AppendByte(';')
-	logf.AppendInt(buf, int64(clr2))
-	buf.AppendByte('m')
-	fn()
-	buf.AppendString("\x1b[0m")
+	es.Compile(NewStyle(clr1, clr2)).At(buf, fn)
 }
 
 // At3 calls the given fn, wrapped with the escape sequence,
 // based on the given codes.
+//
+// Deprecated: Use Compile with a Style instead.
 func (es EscapeSequence) At3(buf *logf.Buffer, clr1, clr2, clr3 EscapeCode, fn func()) {
-	if es.NoColor {
-		fn()
-
-		return
-	}
-
-	buf.AppendString("\x1b[")
-	logf.AppendInt(buf, int64(clr1))
-	buf.AppendByte(';')
-	logf.AppendInt(buf, int64(clr2))
-	buf.AppendByte(';')
-	logf.AppendInt(buf, int64(clr2))
-	buf.AppendByte('m')
-	fn()
-	buf.AppendString("\x1b[0m")
+	es.Compile(NewStyle(clr1, clr2, clr3)).At(buf, fn)
 }
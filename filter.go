@@ -0,0 +1,175 @@
+package logftext
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+
+	"github.com/ssgreg/logf"
+)
+
+// Action specifies what Encoder does with a field.
+type Action int8
+
+// Possible Action values.
+const (
+	// ActionKeep keeps the field as is.
+	ActionKeep Action = iota
+	// ActionRedact replaces the field value with "***".
+	ActionRedact
+	// ActionDrop drops the field entirely.
+	ActionDrop
+)
+
+// redacted replaces values of fields with ActionRedact.
+const redacted = "***"
+
+// keyAction returns the Action for the field with the given key. The
+// strongest Action of FilterKey, DenyKeys, AllowKeys and RedactKeys wins.
+// AllowKeys is not applied to keys inside objects.
+func (s *sharedEncoder) keyAction(key string, nested bool) Action {
+	a := ActionKeep
+	if s.FilterKey != nil {
+		a = s.FilterKey(key)
+	}
+	if a == ActionDrop {
+		return a
+	}
+	if matchKey(s.DenyKeys, key) || (!nested && len(s.AllowKeys) != 0 && !matchKey(s.AllowKeys, key)) {
+		return ActionDrop
+	}
+	if a == ActionKeep && matchKey(s.RedactKeys, key) {
+		a = ActionRedact
+	}
+
+	return a
+}
+
+// matchKey reports whether the given key matches one of the given keys
+// or glob patterns.
+func matchKey(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if matchPattern(p, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPattern reports whether the given key matches the given key or
+// glob pattern in path.Match syntax. Malformed patterns match nothing.
+func matchPattern(pattern, key string) bool {
+	if pattern == key {
+		return true
+	}
+	ok, _ := path.Match(pattern, key)
+
+	return ok
+}
+
+// acceptField encodes the given field applying key filters.
+func (f *encoder) acceptField(field logf.Field) {
+	if f.filterKeys {
+		switch f.keyAction(field.Key, false) {
+		case ActionDrop:
+			return
+		case ActionRedact:
+			f.EncodeFieldString(field.Key, redacted)
+
+			return
+		}
+	}
+	field.Accept(f)
+}
+
+// filterNested applies key filters to keys of objects inside the JSON
+// value encoded starting from the given position.
+func (f *encoder) filterNested(start int) {
+	if bytes.IndexByte(f.buf.Data[start:], ':') == -1 {
+		// No objects inside.
+		return
+	}
+
+	f.scratch.Reset()
+	f.scratch.AppendBytes(f.buf.Data[start:])
+	f.buf.Data = f.buf.Data[:start]
+
+	data := f.scratch.Bytes()
+	for i := 0; i < len(data); {
+		if data[i] != '"' {
+			f.buf.AppendByte(data[i])
+			i++
+
+			continue
+		}
+
+		n := scanValue(data[i:])
+		if n == -1 {
+			f.buf.AppendBytes(data[i:])
+
+			return
+		}
+		key := data[i : i+n]
+		i += n
+		if i == len(data) || data[i] != ':' {
+			// A string value.
+			f.buf.AppendBytes(key)
+
+			continue
+		}
+
+		switch f.keyAction(nestedKey(key), true) {
+		case ActionKeep:
+			f.buf.AppendBytes(key)
+		case ActionRedact:
+			f.buf.AppendBytes(key)
+			f.buf.AppendString(`:"` + redacted + `"`)
+			i += 1 + nestedValueLen(data[i+1:])
+		case ActionDrop:
+			i += 1 + nestedValueLen(data[i+1:])
+			switch {
+			case i < len(data) && data[i] == ',':
+				i++
+			case f.buf.Back() == ',':
+				f.buf.Data = f.buf.Data[:f.buf.Len()-1]
+			}
+		}
+	}
+}
+
+// nestedKey returns the unquoted JSON object key.
+func nestedKey(quoted []byte) string {
+	if bytes.IndexByte(quoted, '\\') == -1 {
+		return string(quoted[1 : len(quoted)-1])
+	}
+
+	var key string
+	if json.Unmarshal(quoted, &key) != nil {
+		return string(quoted[1 : len(quoted)-1])
+	}
+
+	return key
+}
+
+// nestedValueLen returns the length of the JSON value at the beginning of
+// the given data.
+func nestedValueLen(data []byte) int {
+	if len(data) != 0 {
+		switch data[0] {
+		case '"', '[', '{':
+			if n := scanValue(data); n != -1 {
+				return n
+			}
+
+			return len(data)
+		}
+	}
+
+	n := 0
+	for n < len(data) && !isNestedDelimiter(data[n]) {
+		n++
+	}
+
+	return n
+}
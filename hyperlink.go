@@ -0,0 +1,105 @@
+package logftext
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ssgreg/logf"
+)
+
+// Kinds of linkPart.
+const (
+	linkText int8 = iota
+	linkPath
+	linkLine
+)
+
+// linkPart is a literal text or a placeholder of the caller URL template.
+type linkPart struct {
+	kind int8
+	text string
+}
+
+// parseLinkTemplate splits the given caller URL template into parts, so
+// the URL is built without allocations for each entry. A file URL is
+// used if no template is specified.
+func parseLinkTemplate(tmpl string) []linkPart {
+	if tmpl == "" {
+		tmpl = "file://{path}"
+	}
+
+	var parts []linkPart
+	for tmpl != "" {
+		kind, found := linkPath, strings.Index(tmpl, "{path}")
+		if line := strings.Index(tmpl, "{line}"); line != -1 && (found == -1 || line < found) {
+			kind, found = linkLine, line
+		}
+		if found == -1 {
+			parts = append(parts, linkPart{linkText, tmpl})
+
+			break
+		}
+		if found != 0 {
+			parts = append(parts, linkPart{linkText, tmpl[:found]})
+		}
+		parts = append(parts, linkPart{kind: kind})
+		// Both placeholders have the same length.
+		tmpl = tmpl[found+len("{path}"):]
+	}
+
+	return parts
+}
+
+// hasCallerLink reports whether the caller should be printed as
+// a hyperlink. A file URL requires an absolute path.
+func (s *sharedEncoder) hasCallerLink(caller logf.EntryCaller) bool {
+	if !s.hyperlinks {
+		return false
+	}
+
+	return s.CallerURL != "" || (caller.File != "" && os.IsPathSeparator(caller.File[0]))
+}
+
+// beginCallerLink appends the escape sequence that starts the caller
+// hyperlink.
+func (s *sharedEncoder) beginCallerLink(buf *logf.Buffer, caller logf.EntryCaller) {
+	buf.AppendString("\x1b]8;;")
+	for _, p := range s.linkParts {
+		switch p.kind {
+		case linkText:
+			buf.AppendString(p.text)
+		case linkPath:
+			appendURLPath(buf, caller.File)
+		case linkLine:
+			logf.AppendInt(buf, int64(caller.Line))
+		}
+	}
+	buf.AppendString("\x1b\\")
+}
+
+// endLink appends the escape sequence that ends a hyperlink.
+func endLink(buf *logf.Buffer) {
+	buf.AppendString("\x1b]8;;\x1b\\")
+}
+
+// appendURLPath appends the given file path with slashes as separators
+// escaped the same way as url.URL.EscapedPath does.
+func appendURLPath(buf *logf.Buffer, path string) {
+	const hex = "0123456789ABCDEF"
+
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case os.IsPathSeparator(c):
+			buf.AppendByte('/')
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			buf.AppendByte(c)
+		case strings.IndexByte("-_.~$&+,:;=@", c) != -1:
+			buf.AppendByte(c)
+		default:
+			buf.AppendByte('%')
+			buf.AppendByte(hex[c>>4])
+			buf.AppendByte(hex[c&15])
+		}
+	}
+}
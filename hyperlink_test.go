@@ -0,0 +1,34 @@
+package logftext
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendURLPath(t *testing.T) {
+	for _, path := range []string{
+		"/a/b/c/f.go",
+		"/a b/f 1.go",
+		"/a/%?#[]!*'()/f.go",
+		"/a/$&+,:;=@-_.~/f.go",
+		"/путь/日本.go",
+	} {
+		buf := logf.NewBuffer()
+		appendURLPath(buf, path)
+		require.Equal(t, (&url.URL{Path: path}).EscapedPath(), buf.String())
+	}
+}
+
+func TestParseLinkTemplate(t *testing.T) {
+	require.Equal(t, []linkPart{{linkText, "file://"}, {kind: linkPath}}, parseLinkTemplate(""))
+	require.Equal(t, []linkPart{
+		{linkText, "idea://open?file="},
+		{kind: linkPath},
+		{linkText, "&line="},
+		{kind: linkLine},
+	}, parseLinkTemplate("idea://open?file={path}&line={line}"))
+	require.Equal(t, []linkPart{{kind: linkLine}, {kind: linkPath}}, parseLinkTemplate("{line}{path}"))
+}
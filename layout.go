@@ -0,0 +1,21 @@
+package logftext
+
+// Layout specifies the placement of fields in the text format.
+type Layout int8
+
+// Possible Layout values.
+const (
+	// LayoutLine places all fields on the same line with the message:
+	//
+	// 	Jan  1 00:00:00.000 |INFO| main: message key="value" @c/f.go:6
+	LayoutLine Layout = iota
+
+	// LayoutExpanded places each field on its own indented line:
+	//
+	// 	Jan  1 00:00:00.000 |INFO| main: message @c/f.go:6
+	// 	    key: "value"
+	LayoutExpanded
+)
+
+// indent is the indent of continuation lines and expanded fields.
+const indent = "    "
@@ -0,0 +1,110 @@
+package logftext
+
+import (
+	"github.com/ssgreg/logf"
+)
+
+// Format specifies the output format of Encoder.
+type Format int8
+
+// Possible Format values.
+const (
+	// FormatText is the default human-oriented colored text format:
+	//
+	// 	Jan  1 00:00:00.000 |INFO| main: message key="value" @c/f.go:6
+	FormatText Format = iota
+
+	// FormatLogfmt is the strict logfmt format without colors suitable for
+	// tools like Loki or Heroku log drains:
+	//
+	// 	time=2006-01-02T15:04:05Z level=info logger=main msg=message key=value caller=c/f.go:6
+	FormatLogfmt
+)
+
+// Keys of predefined fields in logfmt format.
+const (
+	logfmtKeyTime   = "time"
+	logfmtKeyLevel  = "level"
+	logfmtKeyName   = "logger"
+	logfmtKeyMsg    = "msg"
+	logfmtKeyCaller = "caller"
+)
+
+func (f *encoder) encodeLogfmtHeader(e logf.Entry) {
+	f.EncodeFieldTime(logfmtKeyTime, e.Time)
+
+	f.addKey(logfmtKeyLevel)
+	f.buf.AppendString(e.Level.String())
+
+	if !f.DisableFieldName && e.LoggerName != "" {
+		f.EncodeFieldString(logfmtKeyName, e.LoggerName)
+	}
+
+	f.EncodeFieldString(logfmtKeyMsg, e.Text)
+}
+
+// quoteLogfmtValue converts the JSON value encoded starting from the given
+// position to the logfmt value.
+//
+// JSON strings are valid logfmt quoted strings, so the quotes are removed
+// if they are not needed only. Arrays and objects are quoted as a whole.
+func (f *encoder) quoteLogfmtValue(start int) {
+	v := f.buf.Data[start:]
+	if len(v) == 0 {
+		f.buf.AppendString(`""`)
+
+		return
+	}
+
+	switch v[0] {
+	case '"':
+		if len(v) > 2 && !needsQuoting(v[1:len(v)-1]) {
+			f.unquote(start)
+		}
+	case '[', '{':
+		f.scratch.Reset()
+		f.scratch.AppendBytes(v)
+		f.buf.Data = f.buf.Data[:start]
+		f.buf.AppendByte('"')
+		logf.EscapeByteString(f.buf, f.scratch.Bytes())
+		f.buf.AppendByte('"')
+	}
+}
+
+// unquote removes quotes of the JSON string encoded starting from the
+// given position.
+func (f *encoder) unquote(start int) {
+	v := f.buf.Data[start:]
+	copy(v, v[1:len(v)-1])
+	f.buf.Data = f.buf.Data[:f.buf.Len()-2]
+}
+
+// needsQuoting reports whether the given JSON-escaped string content needs
+// to be quoted in logfmt or in the text format.
+func needsQuoting(s []byte) bool {
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendLogfmtKey appends the given key replacing characters that are not
+// allowed in logfmt keys with underscores.
+func appendLogfmtKey(buf *logf.Buffer, k string) {
+	if k == "" {
+		buf.AppendByte('_')
+
+		return
+	}
+
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			c = '_'
+		}
+		buf.AppendByte(c)
+	}
+}
@@ -0,0 +1,107 @@
+package logftext
+
+// formatNested reformats the JSON object or array encoded starting from
+// the given position adding syntax coloring and, if IndentObjects is set,
+// line breaks with indentation.
+//
+// Objects and arrays are encoded by the JSON type encoder and then scanned
+// again here instead of being encoded natively. That costs an extra pass
+// over each of them if colors or IndentObjects are enabled, but keeps all
+// logf type encoders and their escaping as is.
+func (f *encoder) formatNested(start int) {
+	f.scratch.Reset()
+	f.scratch.AppendBytes(f.buf.Data[start:])
+	f.buf.Data = f.buf.Data[:start]
+
+	data := f.scratch.Bytes()
+	depth := 0
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			i++
+		case '{', '[':
+			f.buf.AppendByte(c)
+			depth++
+			i++
+			if f.IndentObjects && i < len(data) && data[i] != '}' && data[i] != ']' {
+				f.appendNestedIndent(depth)
+			}
+		case '}', ']':
+			depth--
+			if f.IndentObjects && data[i-1] != '{' && data[i-1] != '[' {
+				f.appendNestedIndent(depth)
+			}
+			f.buf.AppendByte(c)
+			i++
+		case ',':
+			f.buf.AppendByte(c)
+			i++
+			if f.IndentObjects {
+				f.appendNestedIndent(depth)
+			}
+		case ':':
+			f.buf.AppendByte(c)
+			i++
+			if f.IndentObjects {
+				f.buf.AppendByte(' ')
+			}
+		case '"':
+			n := scanValue(data[i:])
+			if n == -1 {
+				n = len(data) - i
+			}
+			seq := f.pal.str
+			if i+n < len(data) && data[i+n] == ':' {
+				seq = f.pal.key
+			}
+			f.appendNestedToken(seq, data[i:i+n])
+			i += n
+		default:
+			n := i
+			for n < len(data) && !isNestedDelimiter(data[n]) {
+				n++
+			}
+			seq := f.pal.number
+			switch c {
+			case 't', 'f':
+				seq = f.pal.bool
+			case 'n':
+				seq = f.pal.nil
+			}
+			f.appendNestedToken(seq, data[i:n])
+			i = n
+		}
+	}
+}
+
+// appendNestedToken appends the given token with the given style. The
+// style of the whole value is restored after the token.
+func (f *encoder) appendNestedToken(seq StyleSeq, token []byte) {
+	seq.Begin(f.buf)
+	f.buf.AppendBytes(token)
+	seq.End(f.buf)
+	if seq.prefix != "" {
+		f.valueSeq.Begin(f.buf)
+	}
+}
+
+// appendNestedIndent starts a new line of an indented object or array.
+// Lines are indented like wrapped and expanded lines, so they never start
+// at the first column and can't be confused with log entries.
+func (f *encoder) appendNestedIndent(depth int) {
+	f.buf.AppendByte('\n')
+	f.buf.AppendString(indent)
+	for i := 0; i < depth; i++ {
+		f.buf.AppendString("  ")
+	}
+}
+
+func isNestedDelimiter(c byte) bool {
+	switch c {
+	case ',', ':', '[', ']', '{', '}', ' ', '\t', '\r', '\n':
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package logftext
+
+import (
+	"github.com/ssgreg/logf"
+)
+
+// fieldRef refers to a logger's or an entry's field being ordered.
+type fieldRef struct {
+	key     string
+	rank    int
+	derived bool
+	index   int
+}
+
+// fieldRank returns the rank of the field with the given key. Fields
+// matching FirstKeys have negative ranks in order of patterns, fields
+// matching LastKeys have positive ones, all other fields have zero rank.
+func (s *sharedEncoder) fieldRank(key string) int {
+	for i, p := range s.FirstKeys {
+		if matchPattern(p, key) {
+			return i - len(s.FirstKeys)
+		}
+	}
+	for i, p := range s.LastKeys {
+		if matchPattern(p, key) {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// less reports whether field a must be encoded before field b.
+func (s *sharedEncoder) less(a, b *fieldRef) bool {
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+
+	return s.SortFields && a.key < b.key
+}
+
+// encodeOrderedFields encodes logger's and entry's fields in order
+// specified by SortFields, FirstKeys and LastKeys. Logger's fields are
+// cached as a list of segments, one for each field, so they can be
+// mixed with entry's fields.
+func (f *encoder) encodeOrderedFields(e logf.Entry, wrap bool, key cacheKey) {
+	var segments []byte
+	var ends []int
+	if !wrap && !f.truncate {
+		var ok bool
+		segments, ends, ok = f.Cache.get(key)
+		if !ok {
+			segments, ends = f.encodeSegments(e, key)
+		}
+	}
+
+	f.order = f.order[:0]
+	for i, field := range e.DerivedFields {
+		f.order = append(f.order, fieldRef{field.Key, f.fieldRank(field.Key), true, i})
+	}
+	for i, field := range e.Fields {
+		f.order = append(f.order, fieldRef{field.Key, f.fieldRank(field.Key), false, i})
+	}
+
+	// Insertion sort is stable and does not allocate. The number of fields
+	// is usually small.
+	for i := 1; i < len(f.order); i++ {
+		for j := i; j > 0 && f.less(&f.order[j], &f.order[j-1]); j-- {
+			f.order[j], f.order[j-1] = f.order[j-1], f.order[j]
+		}
+	}
+
+	for _, ref := range f.order {
+		if ref.derived && segments != nil {
+			begin := 0
+			if ref.index != 0 {
+				begin = ends[ref.index-1]
+			}
+			f.buf.AppendBytes(segments[begin:ends[ref.index]])
+
+			continue
+		}
+
+		field := e.Fields[ref.index]
+		if ref.derived {
+			field = e.DerivedFields[ref.index]
+		}
+		start := f.buf.Len()
+		f.acceptField(field)
+		if wrap {
+			f.wrap(start)
+		}
+	}
+}
+
+// encodeSegments encodes logger's fields and returns them with the end of
+// each field. The result is cached unless fields have details.
+func (f *encoder) encodeSegments(e logf.Entry, key cacheKey) ([]byte, []int) {
+	start := f.buf.Len()
+	details := len(f.details)
+
+	ends := make([]int, 0, len(e.DerivedFields))
+	for _, field := range e.DerivedFields {
+		f.acceptField(field)
+		ends = append(ends, f.buf.Len()-start)
+	}
+
+	segments := make([]byte, f.buf.Len()-start)
+	copy(segments, f.buf.Data[start:])
+	f.buf.Data = f.buf.Data[:start]
+
+	if len(f.details) == details {
+		f.Cache.set(key, segments, ends)
+	}
+
+	return segments, ends
+}
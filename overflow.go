@@ -0,0 +1,208 @@
+package logftext
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/ssgreg/logf"
+)
+
+// Overflow specifies how Encoder handles lines wider than
+// EncoderConfig.Width.
+type Overflow int8
+
+// Possible Overflow values.
+const (
+	// OverflowNone leaves long lines as is.
+	OverflowNone Overflow = iota
+
+	// OverflowWrap moves fields that do not fit the line to indented
+	// continuation lines:
+	//
+	// 	Jan  1 00:00:00.000 |INFO| main: message key="value"
+	// 	    another="value" @c/f.go:6
+	OverflowWrap
+
+	// OverflowTruncate cuts long field values to fit the line and marks
+	// them with an ellipsis. Keys and short values are kept:
+	//
+	// 	Jan  1 00:00:00.000 |INFO| main: message key="long val…" n=200
+	OverflowTruncate
+)
+
+// wrap moves the field encoded starting from the given position to a new
+// line if it does not fit the current one. The separator before the field
+// is replaced with a line break and an indent.
+func (f *encoder) wrap(start int) {
+	width := visibleWidth(f.buf.Data[start:])
+	f.fullWidth += width
+	if f.lineWidth+width <= f.Width {
+		f.lineWidth += width
+
+		return
+	}
+
+	sep := bytes.IndexByte(f.buf.Data[start:], ' ')
+	if sep == -1 {
+		f.lineWidth += width
+
+		return
+	}
+	sep += start
+
+	f.scratch.Reset()
+	f.scratch.AppendBytes(f.buf.Data[sep+1:])
+	f.buf.Data = f.buf.Data[:sep]
+	f.buf.AppendByte('\n')
+	f.buf.AppendString(indent)
+	f.buf.AppendBytes(f.scratch.Bytes())
+	f.lineWidth = len(indent) + width - 1
+}
+
+// valueSpan is the position and the visible width of a field value
+// encoded in the current line.
+type valueSpan struct {
+	start int
+	end   int
+	width int
+}
+
+// minTruncatedWidth is the width long values are never cut below, so
+// they stay recognizable even if the rest of the line is wider than Width.
+const minTruncatedWidth = 10
+
+// truncateValues shortens field values with an ellipsis so that the line
+// fits Width. All long values are cut to the same width, the largest one
+// that lets the line fit, but not below minTruncatedWidth. Keys, the
+// message, the caller and short values are kept as is, so the line can
+// still be wider than Width.
+func (f *encoder) truncateValues() {
+	excess := visibleWidth(f.buf.Data[f.startBufLen:]) - f.Width
+	if excess <= 0 || len(f.values) == 0 {
+		return
+	}
+
+	total, longest := 0, 0
+	for i := range f.values {
+		v := &f.values[i]
+		v.width = visibleWidth(f.buf.Data[v.start:v.end])
+		total += v.width
+		if v.width > longest {
+			longest = v.width
+		}
+	}
+
+	// Find the largest limit values fit in with a binary search.
+	budget := total - excess
+	lo, hi := minTruncatedWidth, longest
+	if lo >= hi {
+		return
+	}
+	for lo < hi {
+		m := (lo + hi + 1) / 2
+		if limitedWidth(f.values, m) <= budget {
+			lo = m
+		} else {
+			hi = m - 1
+		}
+	}
+
+	first := f.values[0].start
+	f.scratch.Reset()
+	f.scratch.AppendBytes(f.buf.Data[first:])
+	f.buf.Data = f.buf.Data[:first]
+
+	data := f.scratch.Bytes()
+	prev := 0
+	for _, v := range f.values {
+		start, end := v.start-first, v.end-first
+		f.buf.AppendBytes(data[prev:start])
+		appendTruncated(f.buf, data[start:end], v.width, lo)
+		prev = end
+	}
+	f.buf.AppendBytes(data[prev:])
+}
+
+// limitedWidth returns the total width of the given values cut to the
+// given limit.
+func limitedWidth(values []valueSpan, limit int) int {
+	n := 0
+	for _, v := range values {
+		if v.width > limit {
+			n += limit
+		} else {
+			n += v.width
+		}
+	}
+
+	return n
+}
+
+// appendTruncated appends the given value of the given visible width cut
+// to the given limit. The last visible character is replaced with an
+// ellipsis, the closing quote of a quoted string is kept. JSON escapes
+// like \" are never split. Escape sequences after the cut are dropped,
+// a style that is on at the cut is reset.
+func appendTruncated(buf *logf.Buffer, v []byte, width, limit int) {
+	if width <= limit {
+		buf.AppendBytes(v)
+
+		return
+	}
+
+	quoted := len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"'
+	keep := limit - 1
+	if quoted {
+		keep--
+		v = v[:len(v)-1]
+	}
+
+	n := 0
+	styled := false
+	for i := 0; i < len(v); {
+		if l := escapeLen(v[i:]); l != 0 {
+			if v[i+l-1] == 'm' {
+				styled = string(v[i:i+l]) != "\x1b[0m"
+			}
+			buf.AppendBytes(v[i : i+l])
+			i += l
+
+			continue
+		}
+		l, w := jsonEscapeLen(v[i:]), 0
+		if l != 0 {
+			w = l
+		} else {
+			var r rune
+			r, l = utf8.DecodeRune(v[i:])
+			w = runeWidth(r)
+		}
+		if n+w > keep {
+			break
+		}
+		buf.AppendBytes(v[i : i+l])
+		i += l
+		n += w
+	}
+
+	buf.AppendString("…")
+	if quoted {
+		buf.AppendByte('"')
+	}
+	if styled {
+		buf.AppendString("\x1b[0m")
+	}
+}
+
+// jsonEscapeLen returns the length of the JSON escape like \n or \u00e9
+// at the beginning of the given text or zero if there is no escape.
+func jsonEscapeLen(text []byte) int {
+	if len(text) < 2 || text[0] != '\\' {
+		return 0
+	}
+	if text[1] == 'u' && len(text) >= 6 {
+		return 6
+	}
+
+	return 2
+}
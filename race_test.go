@@ -0,0 +1,7 @@
+// +build race
+
+package logftext
+
+func init() {
+	raceEnabled = true
+}
@@ -0,0 +1,150 @@
+package logftext
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// isStack reports whether the given text looks like a Go stack trace, i.e.
+// has a line with a function followed by a line with its location.
+func isStack(text string) bool {
+	for {
+		found := strings.Index(text, "\n\t")
+		if found == -1 {
+			return false
+		}
+		text = text[found+1:]
+
+		end := strings.IndexByte(text, '\n')
+		if end == -1 {
+			end = len(text)
+		}
+		if _, ok := frameLocation(text[:end]); ok {
+			return true
+		}
+	}
+}
+
+// appendStack appends the given stack trace one frame per line in form of
+// "file:line function". Runtime and testing frames are dropped. Lines that
+// are not frames (e.g. a goroutine header) are printed as is.
+func (f *encoder) appendStack(text string) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if i+1 < len(lines) {
+			if location, ok := frameLocation(lines[i+1]); ok {
+				i++
+				fn := frameFunction(line)
+				if isNoiseFrame(fn) {
+					continue
+				}
+				f.appendDetailLine("", 1, func() {
+					f.pal.caller.AppendString(f.buf, location)
+					f.buf.AppendByte(' ')
+					f.buf.AppendString(fn)
+				})
+
+				continue
+			}
+		}
+
+		if line != "" {
+			f.appendDetailLine("", 1, func() {
+				f.buf.AppendString(line)
+			})
+		}
+	}
+}
+
+// frameLocation parses the location line of a stack frame like
+// "\t/go/src/a/b.go:12 +0x1d" and returns the shortened "a/b.go:12".
+func frameLocation(line string) (string, bool) {
+	if !strings.HasPrefix(line, "\t") {
+		return "", false
+	}
+	line = strings.TrimSpace(line)
+	if found := strings.IndexByte(line, ' '); found != -1 {
+		line = line[:found]
+	}
+
+	sep := strings.LastIndexByte(line, ':')
+	if sep == -1 || sep == len(line)-1 || !strings.HasSuffix(line[:sep], ".go") {
+		return "", false
+	}
+	for _, c := range line[sep+1:] {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+
+	return shortenPath(line[:sep]) + line[sep:], true
+}
+
+// frameFunction returns the function name of a stack frame without
+// arguments, e.g. "main.(*T).f" for "main.(*T).f(0xc000010000)".
+func frameFunction(line string) string {
+	if strings.HasSuffix(line, ")") {
+		if found := strings.LastIndexByte(line, '('); found > 0 && line[found-1] != '.' {
+			return line[:found]
+		}
+	}
+
+	return line
+}
+
+func isNoiseFrame(fn string) bool {
+	fn = strings.TrimPrefix(fn, "created by ")
+
+	return strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "runtime/debug.") || strings.HasPrefix(fn, "testing.")
+}
+
+var (
+	sourcePrefixesOnce sync.Once
+	sourcePrefixes     []string
+)
+
+// shortenPath makes the given source file path relative to the working
+// directory, a module cache, GOPATH or GOROOT.
+func shortenPath(path string) string {
+	sourcePrefixesOnce.Do(func() {
+		sourcePrefixes = detectSourcePrefixes()
+	})
+
+	for _, prefix := range sourcePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return path[len(prefix):]
+		}
+	}
+
+	return path
+}
+
+func detectSourcePrefixes() []string {
+	var prefixes []string
+
+	if wd, err := os.Getwd(); err == nil {
+		prefixes = append(prefixes, filepath.ToSlash(wd)+"/")
+	}
+
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			gopath = filepath.Join(home, "go")
+		}
+	}
+	for _, p := range filepath.SplitList(gopath) {
+		p = filepath.ToSlash(p)
+		prefixes = append(prefixes, p+"/pkg/mod/", p+"/src/")
+	}
+
+	if root := runtime.GOROOT(); root != "" {
+		prefixes = append(prefixes, filepath.ToSlash(root)+"/src/")
+	}
+
+	return prefixes
+}
@@ -0,0 +1,149 @@
+package logftext
+
+import (
+	"github.com/ssgreg/logf"
+)
+
+// Style is a composition of escape codes applied to a text at once.
+//
+// Style methods never modify the Style they are called on, so it's safe
+// to derive new styles from a shared one:
+//
+// 	warn := NewStyle(EscBrightYellow).Reverse()
+type Style []EscapeCode
+
+// NewStyle returns a new Style composed of the given escape codes.
+func NewStyle(codes ...EscapeCode) Style {
+	return Style(nil).With(codes...)
+}
+
+// With returns a copy of the Style with the given escape codes added.
+func (s Style) With(codes ...EscapeCode) Style {
+	r := make(Style, 0, len(s)+len(codes))
+	r = append(r, s...)
+
+	return append(r, codes...)
+}
+
+// Fg returns a copy of the Style with the given text color.
+func (s Style) Fg(c EscapeCode) Style {
+	return s.With(c)
+}
+
+// Bg returns a copy of the Style with the given background color. Text
+// colors are converted to their background forms, e.g. EscRed becomes
+// EscBgRed.
+func (s Style) Bg(c EscapeCode) Style {
+	switch {
+	case c&escKindMask != 0:
+		c |= escBackground
+	case (c >= EscBlack && c <= EscWhite) || (c >= EscBrightBlack && c <= EscBrightWhite):
+		c += EscBgBlack - EscBlack
+	}
+
+	return s.With(c)
+}
+
+// Bold returns a copy of the Style with bold text.
+func (s Style) Bold() Style {
+	return s.With(EscBold)
+}
+
+// Faint returns a copy of the Style with faint text.
+func (s Style) Faint() Style {
+	return s.With(EscFaint)
+}
+
+// Italic returns a copy of the Style with italic text.
+func (s Style) Italic() Style {
+	return s.With(EscItalic)
+}
+
+// Underline returns a copy of the Style with underlined text.
+func (s Style) Underline() Style {
+	return s.With(EscUnderline)
+}
+
+// Reverse returns a copy of the Style with swapped text and background
+// colors.
+func (s Style) Reverse() Style {
+	return s.With(EscReverse)
+}
+
+// CrossedOut returns a copy of the Style with crossed-out text.
+func (s Style) CrossedOut() Style {
+	return s.With(EscCrossedOut)
+}
+
+// StyleSeq is a Style precomputed into escape sequence bytes. Use
+// EscapeSequence.Compile to create it once and apply it as many times as
+// needed without formatting escape codes again.
+//
+// The zero StyleSeq adds no escape sequences.
+type StyleSeq struct {
+	prefix string
+}
+
+// Compile precomputes the escape sequence for the given Style according to
+// the EscapeSequence settings. The result has no escape sequences at all if
+// colors are disabled or the Style is empty.
+func (es EscapeSequence) Compile(s Style) StyleSeq {
+	if es.NoColor {
+		return StyleSeq{}
+	}
+
+	buf := logf.NewBufferWithCapacity(32)
+	for _, code := range s {
+		if es.Level == ColorLevelNone && code.IsColor() {
+			continue
+		}
+		if code.attr()&es.NoAttrs != 0 {
+			continue
+		}
+		if buf.Len() == 0 {
+			buf.AppendString("\x1b[")
+		} else {
+			buf.AppendByte(';')
+		}
+		appendEscapeCode(buf, code.Downgrade(es.Level))
+	}
+	if buf.Len() == 0 {
+		return StyleSeq{}
+	}
+	buf.AppendByte('m')
+
+	return StyleSeq{buf.String()}
+}
+
+// Begin appends the escape sequence that turns the style on.
+func (s StyleSeq) Begin(buf *logf.Buffer) {
+	buf.AppendString(s.prefix)
+}
+
+// End appends the escape sequence that resets the style.
+func (s StyleSeq) End(buf *logf.Buffer) {
+	if s.prefix != "" {
+		buf.AppendString("\x1b[0m")
+	}
+}
+
+// AppendString appends the given text wrapped with the escape sequence.
+func (s StyleSeq) AppendString(buf *logf.Buffer, text string) {
+	s.Begin(buf)
+	buf.AppendString(text)
+	s.End(buf)
+}
+
+// AppendByte appends the given byte wrapped with the escape sequence.
+func (s StyleSeq) AppendByte(buf *logf.Buffer, b byte) {
+	s.Begin(buf)
+	buf.AppendByte(b)
+	s.End(buf)
+}
+
+// At calls the given fn, wrapped with the escape sequence.
+func (s StyleSeq) At(buf *logf.Buffer, fn func()) {
+	s.Begin(buf)
+	fn()
+	s.End(buf)
+}
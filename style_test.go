@@ -0,0 +1,31 @@
+package logftext
+
+import (
+	"testing"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStyleCompile(t *testing.T) {
+	base := NewStyle(EscRed)
+	style := base.Bg(EscBrightWhite).Bold().Underline()
+	require.Equal(t, Style{EscRed}, base)
+
+	buf := logf.NewBuffer()
+	EscapeSequence{}.Compile(style).AppendString(buf, "text")
+	EscapeSequence{}.Compile(NewStyle().Bg(Color256(1))).AppendByte(buf, '!')
+	EscapeSequence{NoColor: true}.Compile(style).AppendString(buf, "text")
+	EscapeSequence{}.Compile(Style{}).AppendString(buf, "text")
+
+	require.Equal(t, "\x1b[31;107;1;4mtext\x1b[0m\x1b[48;5;1m!\x1b[0mtexttext", buf.String())
+}
+
+func TestEscapeSequenceAt3(t *testing.T) {
+	buf := logf.NewBuffer()
+	EscapeSequence{}.At3(buf, EscBold, EscRed, EscBgWhite, func() {
+		buf.AppendString("text")
+	})
+
+	require.Equal(t, "\x1b[1;31;47mtext\x1b[0m", buf.String())
+}
@@ -0,0 +1,185 @@
+package logftext
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Attrs is a set of text attributes.
+type Attrs uint16
+
+// Text attributes.
+const (
+	AttrBold Attrs = 1 << iota
+	AttrFaint
+	AttrItalic
+	AttrUnderline
+	AttrBlink
+	AttrReverse
+	AttrConceal
+	AttrCrossedOut
+
+	AllAttrs = AttrBold | AttrFaint | AttrItalic | AttrUnderline | AttrBlink | AttrReverse | AttrConceal | AttrCrossedOut
+)
+
+// attr returns the text attribute the EscapeCode turns on or zero if it's
+// not a text attribute.
+func (c EscapeCode) attr() Attrs {
+	switch c {
+	case EscBold:
+		return AttrBold
+	case EscFaint:
+		return AttrFaint
+	case EscItalic:
+		return AttrItalic
+	case EscUnderline:
+		return AttrUnderline
+	case EscSlowBlink, EscRapidBlink:
+		return AttrBlink
+	case EscReverse:
+		return AttrReverse
+	case EscConseal:
+		return AttrConceal
+	case EscCrossedOut:
+		return AttrCrossedOut
+	}
+
+	return 0
+}
+
+// TermCaps describes escape sequence capabilities of a terminal.
+type TermCaps struct {
+	// Colors specifies the color level supported by the terminal.
+	Colors ColorLevel
+
+	// Attrs specifies text attributes supported by the terminal.
+	Attrs Attrs
+
+	// Hyperlinks specifies whether the terminal supports OSC 8 hyperlinks.
+	Hyperlinks bool
+}
+
+// Dumb reports whether the terminal supports neither colors nor text
+// attributes.
+func (c TermCaps) Dumb() bool {
+	return c.Colors == ColorLevelNone && c.Attrs == 0
+}
+
+// termInfo is a small built-in subset of the terminfo database. Terminal
+// names are matched exactly first and then by the part before the first
+// dash or dot, e.g. "rxvt-unicode" matches "rxvt".
+var termInfo = map[string]TermCaps{
+	"dumb":          {ColorLevelNone, 0, false},
+	"vt52":          {ColorLevelNone, 0, false},
+	"vt100":         {ColorLevelNone, AttrBold | AttrUnderline | AttrBlink | AttrReverse, false},
+	"vt102":         {ColorLevelNone, AttrBold | AttrUnderline | AttrBlink | AttrReverse, false},
+	"vt220":         {ColorLevelNone, AttrBold | AttrUnderline | AttrBlink | AttrReverse, false},
+	"ansi":          {ColorLevel16, AttrBold | AttrUnderline | AttrBlink | AttrReverse, false},
+	"linux":         {ColorLevel16, AttrBold | AttrFaint | AttrUnderline | AttrBlink | AttrReverse, false},
+	"cygwin":        {ColorLevel16, AttrBold | AttrUnderline | AttrReverse, false},
+	"screen":        {ColorLevel16, AttrBold | AttrFaint | AttrUnderline | AttrBlink | AttrReverse, false},
+	"rxvt":          {ColorLevel16, AttrBold | AttrItalic | AttrUnderline | AttrBlink | AttrReverse, false},
+	"putty":         {ColorLevel16, AttrBold | AttrFaint | AttrUnderline | AttrBlink | AttrReverse, false},
+	"xterm":         {ColorLevel16, AllAttrs, false},
+	"tmux":          {ColorLevel16, AllAttrs, false},
+	"konsole":       {ColorLevel16, AllAttrs, false},
+	"gnome":         {ColorLevel16, AllAttrs, false},
+	"alacritty":     {ColorLevelTrueColor, AllAttrs, true},
+	"wezterm":       {ColorLevelTrueColor, AllAttrs, true},
+	"foot":          {ColorLevelTrueColor, AllAttrs, true},
+	"xterm-kitty":   {ColorLevelTrueColor, AllAttrs, true},
+	"xterm-ghostty": {ColorLevelTrueColor, AllAttrs, true},
+}
+
+// LookupTermCaps returns capabilities of the terminal with the given name
+// as specified in the TERM environment variable. Unknown terminals are
+// supposed to support 16 colors and all text attributes.
+//
+// Suffixes "-256color", "-direct" and "-truecolor" raise the supported
+// color level.
+func LookupTermCaps(term string) TermCaps {
+	caps, ok := termInfo[term]
+	if !ok {
+		name := term
+		if found := strings.IndexAny(term, "-."); found != -1 {
+			name = term[:found]
+		}
+		caps, ok = termInfo[name]
+		if !ok {
+			caps = TermCaps{ColorLevel16, AllAttrs, false}
+		}
+	}
+
+	if caps.Colors != ColorLevelNone {
+		switch {
+		case strings.HasSuffix(term, "-direct") || strings.HasSuffix(term, "-truecolor"):
+			caps.Colors = ColorLevelTrueColor
+		case strings.Contains(term, "256color") && caps.Colors < ColorLevel256:
+			caps.Colors = ColorLevel256
+		}
+	}
+
+	return caps
+}
+
+// DetectTermCaps detects capabilities of the current terminal using TERM
+// environment variable. The color level is refined with DetectColorLevel
+// unless the terminal does not support colors at all. Hyperlinks support
+// is refined with DetectHyperlinks.
+func DetectTermCaps() TermCaps {
+	caps := LookupTermCaps(os.Getenv("TERM"))
+	if caps.Colors != ColorLevelNone {
+		caps.Colors = DetectColorLevel()
+	}
+	if !caps.Dumb() {
+		caps.Hyperlinks = DetectHyperlinks(caps.Hyperlinks)
+	}
+
+	return caps
+}
+
+// ForceColors returns the given capabilities adjusted for output with
+// colors forced, e.g. with ColorAlways or FORCE_COLOR. CI systems like
+// Jenkins often set TERM=dumb but display colors anyway, so capabilities of
+// a dumb terminal are replaced with the color level detected by
+// DetectColorLevel (16 colors at least) and all text attributes.
+func ForceColors(caps TermCaps) TermCaps {
+	if !caps.Dumb() {
+		return caps
+	}
+
+	caps.Colors = DetectColorLevel()
+	if caps.Colors == ColorLevelNone {
+		caps.Colors = ColorLevel16
+	}
+	caps.Attrs = AllAttrs
+
+	return caps
+}
+
+// DetectHyperlinks detects whether the current terminal supports OSC 8
+// hyperlinks using environment variables set by terminal emulators. The
+// given default is returned if nothing is detected. FORCE_HYPERLINK
+// environment variable set to "1" or "0" overrides the detection.
+func DetectHyperlinks(def bool) bool {
+	switch os.Getenv("FORCE_HYPERLINK") {
+	case "1":
+		return true
+	case "0":
+		return false
+	}
+
+	if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("WT_SESSION") != "" {
+		return true
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty":
+		return true
+	}
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+
+	return def
+}
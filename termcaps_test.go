@@ -0,0 +1,53 @@
+package logftext
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupTermCaps(t *testing.T) {
+	require.True(t, LookupTermCaps("dumb").Dumb())
+	require.Equal(t, TermCaps{ColorLevelNone, AttrBold | AttrUnderline | AttrBlink | AttrReverse, false}, LookupTermCaps("vt100"))
+	require.Equal(t, TermCaps{ColorLevel256, AllAttrs, false}, LookupTermCaps("xterm-256color"))
+	require.Equal(t, TermCaps{ColorLevelTrueColor, AllAttrs, false}, LookupTermCaps("xterm-direct"))
+	require.Equal(t, TermCaps{ColorLevel256, AttrBold | AttrFaint | AttrUnderline | AttrBlink | AttrReverse, false}, LookupTermCaps("screen.xterm-256color"))
+	require.Equal(t, TermCaps{ColorLevel16, AllAttrs, false}, LookupTermCaps("unknown"))
+}
+
+func TestDetectHyperlinks(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Env      map[string]string
+		Default  bool
+		Expected bool
+	}{
+		{"Empty", map[string]string{}, false, false},
+		{"Default", map[string]string{}, true, true},
+		{"Kitty", map[string]string{"KITTY_WINDOW_ID": "1"}, false, true},
+		{"ITerm", map[string]string{"TERM_PROGRAM": "iTerm.app"}, false, true},
+		{"OldVTE", map[string]string{"VTE_VERSION": "4601"}, false, false},
+		{"VTE", map[string]string{"VTE_VERSION": "6003"}, false, true},
+		{"Force", map[string]string{"FORCE_HYPERLINK": "1"}, false, true},
+		{"ForceOff", map[string]string{"FORCE_HYPERLINK": "0", "WT_SESSION": "1"}, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			defer setEnv([]string{"FORCE_HYPERLINK", "KITTY_WINDOW_ID", "WT_SESSION", "TERM_PROGRAM", "VTE_VERSION"}, tc.Env)()
+
+			require.Equal(t, tc.Expected, DetectHyperlinks(tc.Default))
+		})
+	}
+}
+
+func TestForceColors(t *testing.T) {
+	defer setEnv([]string{"FORCE_COLOR", "COLORTERM", "TERM"}, map[string]string{"TERM": "dumb"})()
+
+	require.Equal(t, TermCaps{ColorLevel16, AllAttrs, false}, ForceColors(LookupTermCaps("dumb")))
+	require.Equal(t, LookupTermCaps("vt100"), ForceColors(LookupTermCaps("vt100")))
+
+	os.Setenv("FORCE_COLOR", "3")
+	require.Equal(t, TermCaps{ColorLevelTrueColor, AllAttrs, false}, ForceColors(LookupTermCaps("dumb")))
+}
@@ -0,0 +1,197 @@
+package logftext
+
+import (
+	"github.com/ssgreg/logf"
+)
+
+// Theme specifies a Style for each element of a log entry. An element with
+// an empty Style is printed as is.
+type Theme struct {
+	Time   Style
+	Name   Style
+	Msg    Style
+	Key    Style
+	Equal  Style
+	Value  Style
+	Caller Style
+
+	// String, Number, Bool, Nil, Duration, Timestamp and Error specify
+	// styles of values by their type, the Value style is used for values
+	// of other types or if a style is empty. Values inside objects and
+	// arrays are styled as well, keys inside objects use the Key style.
+	String    Style
+	Number    Style
+	Bool      Style
+	Nil       Style
+	Duration  Style
+	Timestamp Style
+	Error     Style
+
+	LevelDebug   Style
+	LevelInfo    Style
+	LevelWarn    Style
+	LevelError   Style
+	LevelUnknown Style
+}
+
+// DefaultTheme returns the theme used by Encoder when no other theme is
+// specified. It's the same as DarkTheme.
+func DefaultTheme() *Theme {
+	return DarkTheme()
+}
+
+// DarkTheme returns the theme suitable for terminals with a dark
+// background.
+func DarkTheme() *Theme {
+	return &Theme{
+		Time:   NewStyle(EscBrightBlack),
+		Name:   NewStyle(EscBrightBlack),
+		Msg:    NewStyle(EscBrightWhite),
+		Key:    NewStyle(EscGreen),
+		Equal:  NewStyle(EscBrightBlack),
+		Caller: NewStyle(EscBrightBlack),
+
+		String:    NewStyle(EscYellow),
+		Number:    NewStyle(EscBrightBlue),
+		Bool:      NewStyle(EscMagenta),
+		Nil:       NewStyle(EscBrightBlack),
+		Duration:  NewStyle(EscCyan),
+		Timestamp: NewStyle(EscBlue),
+		Error:     NewStyle(EscBrightRed),
+
+		LevelDebug:   NewStyle(EscMagenta),
+		LevelInfo:    NewStyle(EscCyan),
+		LevelWarn:    NewStyle(EscBrightYellow).Reverse(),
+		LevelError:   NewStyle(EscBrightRed).Reverse(),
+		LevelUnknown: NewStyle(EscBrightRed),
+	}
+}
+
+// LightTheme returns the theme suitable for terminals with a light
+// background.
+func LightTheme() *Theme {
+	return &Theme{
+		Time:   NewStyle(EscBrightBlack),
+		Name:   NewStyle(EscBrightBlack),
+		Msg:    NewStyle(EscBlack),
+		Key:    NewStyle(EscBlue),
+		Equal:  NewStyle(EscBrightBlack),
+		Caller: NewStyle(EscBrightBlack),
+
+		String:    NewStyle(EscGreen),
+		Number:    NewStyle(EscCyan),
+		Bool:      NewStyle(EscMagenta),
+		Nil:       NewStyle(EscBrightBlack),
+		Duration:  NewStyle(EscBlue),
+		Timestamp: NewStyle(EscBlue),
+		Error:     NewStyle(EscRed).Bold(),
+
+		LevelDebug:   NewStyle(EscMagenta),
+		LevelInfo:    NewStyle(EscBlue),
+		LevelWarn:    NewStyle(EscYellow).Reverse(),
+		LevelError:   NewStyle(EscRed).Reverse(),
+		LevelUnknown: NewStyle(EscRed),
+	}
+}
+
+// HighContrastTheme returns the theme that uses bold bright colors only.
+// It's readable on both dark and light backgrounds.
+func HighContrastTheme() *Theme {
+	return &Theme{
+		Name:   NewStyle().Bold(),
+		Msg:    NewStyle().Bold(),
+		Key:    NewStyle(EscBrightCyan).Bold(),
+		Equal:  NewStyle().Bold(),
+		Caller: NewStyle().Bold(),
+
+		String:    NewStyle(EscBrightYellow),
+		Number:    NewStyle(EscBrightGreen),
+		Bool:      NewStyle(EscBrightMagenta),
+		Nil:       NewStyle().Bold(),
+		Duration:  NewStyle(EscBrightCyan),
+		Timestamp: NewStyle(EscBrightCyan),
+		Error:     NewStyle(EscBrightRed).Bold(),
+
+		LevelDebug:   NewStyle(EscBrightMagenta).Bold().Reverse(),
+		LevelInfo:    NewStyle(EscBrightCyan).Bold().Reverse(),
+		LevelWarn:    NewStyle(EscBrightYellow).Bold().Reverse(),
+		LevelError:   NewStyle(EscBrightRed).Bold().Reverse(),
+		LevelUnknown: NewStyle(EscBrightRed).Bold().Reverse(),
+	}
+}
+
+// palette holds Theme styles compiled for the specific EscapeSequence.
+type palette struct {
+	time   StyleSeq
+	name   StyleSeq
+	msg    StyleSeq
+	key    StyleSeq
+	equal  StyleSeq
+	value  StyleSeq
+	caller StyleSeq
+
+	str       StyleSeq
+	number    StyleSeq
+	bool      StyleSeq
+	nil       StyleSeq
+	duration  StyleSeq
+	timestamp StyleSeq
+	error     StyleSeq
+
+	levelDebug   StyleSeq
+	levelInfo    StyleSeq
+	levelWarn    StyleSeq
+	levelError   StyleSeq
+	levelUnknown StyleSeq
+}
+
+func (t *Theme) compile(es EscapeSequence) palette {
+	return palette{
+		time:   es.Compile(t.Time),
+		name:   es.Compile(t.Name),
+		msg:    es.Compile(t.Msg),
+		key:    es.Compile(t.Key),
+		equal:  es.Compile(t.Equal),
+		value:  es.Compile(t.Value),
+		caller: es.Compile(t.Caller),
+
+		str:       es.Compile(t.valueStyle(t.String)),
+		number:    es.Compile(t.valueStyle(t.Number)),
+		bool:      es.Compile(t.valueStyle(t.Bool)),
+		nil:       es.Compile(t.valueStyle(t.Nil)),
+		duration:  es.Compile(t.valueStyle(t.Duration)),
+		timestamp: es.Compile(t.valueStyle(t.Timestamp)),
+		error:     es.Compile(t.valueStyle(t.Error)),
+
+		levelDebug:   es.Compile(t.LevelDebug),
+		levelInfo:    es.Compile(t.LevelInfo),
+		levelWarn:    es.Compile(t.LevelWarn),
+		levelError:   es.Compile(t.LevelError),
+		levelUnknown: es.Compile(t.LevelUnknown),
+	}
+}
+
+// valueStyle returns the given type-specific style or the Value style if
+// it's empty.
+func (t *Theme) valueStyle(s Style) Style {
+	if len(s) == 0 {
+		return t.Value
+	}
+
+	return s
+}
+
+func (p *palette) level(lvl logf.Level) StyleSeq {
+	switch lvl {
+	case logf.LevelDebug:
+		return p.levelDebug
+	case logf.LevelInfo:
+		return p.levelInfo
+	case logf.LevelWarn:
+		return p.levelWarn
+	case logf.LevelError:
+		return p.levelError
+	default:
+		return p.levelUnknown
+	}
+}
@@ -2,6 +2,7 @@ package logftext
 
 import (
 	"os"
+	"strconv"
 )
 
 // EnableSeqTTY enables possibility to use escape sequences in TTY if possible.
@@ -9,6 +10,20 @@ func EnableSeqTTY(f *os.File, flag bool) bool {
 	return enableSeqTTY(f.Fd(), flag) == nil
 }
 
+// TerminalWidth returns the width of the terminal the given File is bound
+// to. The COLUMNS environment variable is used if the width can't be
+// obtained from the terminal. Zero is returned if the width is unknown.
+func TerminalWidth(f *os.File) int {
+	if width, err := terminalWidth(f.Fd()); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+
+	return 0
+}
+
 // CheckNoColor checks for NO_COLORS environment variable to disable color
 // output.
 //
@@ -8,9 +8,14 @@ import (
 	"unsafe"
 )
 
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
+
 func enableSeqTTY(fd uintptr, flag bool) error {
 	var termios syscall.Termios
-	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&termios)), 0, 0, 0)
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&termios)), 0, 0, 0)
 	if errno != 0 {
 		return errno
 	}
@@ -0,0 +1,77 @@
+// +build linux
+// +build !appengine
+
+package logftext
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForegroundTTY(t *testing.T) {
+	switch os.Getenv("LOGFTEXT_TTY_HELPER") {
+	case "foreground":
+		// Running as a session leader with the pseudo-terminal as the
+		// controlling one. Run the background job like "./prog &" does.
+		if !foregroundTTY(0) {
+			os.Exit(1)
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestForegroundTTY$")
+		cmd.Env = append(os.Environ(), "LOGFTEXT_TTY_HELPER=background")
+		cmd.Stdin = os.Stdin
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if cmd.Run() != nil {
+			os.Exit(2)
+		}
+		os.Exit(0)
+	case "background":
+		// The terminal must not be touched, otherwise the process is
+		// stopped with SIGTTOU.
+		if foregroundTTY(0) {
+			os.Exit(3)
+		}
+		if _, err := queryTTY(osc11Query); err != errBackgroundProcess {
+			os.Exit(4)
+		}
+		os.Exit(0)
+	}
+
+	master, slave := openPTY(t)
+	defer master.Close()
+	defer slave.Close()
+
+	// The pseudo-terminal is not the controlling terminal of the process.
+	require.False(t, foregroundTTY(int(slave.Fd())))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestForegroundTTY$")
+	cmd.Env = append(os.Environ(), "LOGFTEXT_TTY_HELPER=foreground")
+	cmd.Stdin = slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	require.NoError(t, cmd.Run())
+}
+
+func openPTY(t *testing.T) (*os.File, *os.File) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("pseudo-terminals are not available: %v", err)
+	}
+
+	var unlock int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock)))
+	require.Zero(t, errno)
+
+	var n uint32
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n)))
+	require.Zero(t, errno)
+
+	slave, err := os.OpenFile("/dev/pts/"+strconv.Itoa(int(n)), os.O_RDWR|syscall.O_NOCTTY, 0)
+	require.NoError(t, err)
+
+	return master, slave
+}
@@ -0,0 +1,9 @@
+// +build !darwin,!freebsd,!openbsd,!netbsd,!dragonfly,!linux appengine
+
+package logftext
+
+import "errors"
+
+func queryTTY(query string) ([]byte, error) {
+	return nil, errors.New("terminal queries are not supported")
+}
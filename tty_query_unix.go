@@ -0,0 +1,99 @@
+// +build darwin freebsd openbsd netbsd dragonfly linux
+// +build !appengine
+
+package logftext
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// queryTimeout specifies how long to wait for a terminal response.
+const queryTimeout = 500 * time.Millisecond
+
+// errBackgroundProcess is returned by queryTTY if the process is not in
+// the foreground process group of the terminal.
+var errBackgroundProcess = errors.New("process is not in the terminal foreground")
+
+// queryTTY writes the given query to the controlling terminal and returns
+// the terminal response. Reading is stopped as soon as the response to the
+// primary device attributes request is received or on timeout.
+//
+// A background process is stopped by SIGTTOU on changing terminal modes,
+// so the terminal is queried by the foreground process only.
+func queryTTY(query string) ([]byte, error) {
+	fd, err := syscall.Open("/dev/tty", syscall.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	if !foregroundTTY(fd) {
+		return nil, errBackgroundProcess
+	}
+
+	var termios syscall.Termios
+	err = ioctlTermios(fd, ioctlGetTermios, &termios)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disable echo and canonical mode, make reads return after 100ms if no
+	// input is available.
+	raw := termios
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+
+	err = ioctlTermios(fd, ioctlSetTermios, &raw)
+	if err != nil {
+		return nil, err
+	}
+	defer ioctlTermios(fd, ioctlSetTermios, &termios)
+
+	_, err = syscall.Write(fd, []byte(query))
+	if err != nil {
+		return nil, err
+	}
+
+	var response []byte
+	var chunk [64]byte
+	deadline := time.Now().Add(queryTimeout)
+	for time.Now().Before(deadline) && len(response) < 1024 {
+		n, err := syscall.Read(fd, chunk[:])
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, chunk[:n]...)
+
+		// Primary device attributes response is "ESC [ ? ... c".
+		if found := bytes.Index(response, []byte("\x1b[?")); found != -1 {
+			if bytes.IndexByte(response[found:], 'c') != -1 {
+				break
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// foregroundTTY reports whether the process is in the foreground process
+// group of the terminal with the given file descriptor.
+func foregroundTTY(fd int) bool {
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp)))
+
+	return errno == 0 && int(pgrp) == syscall.Getpgrp()
+}
+
+func ioctlTermios(fd int, req uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(termios)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+// +build !darwin,!freebsd,!openbsd,!netbsd,!dragonfly,!linux,!solaris,!windows appengine
+
+package logftext
+
+import "errors"
+
+func terminalWidth(fd uintptr) (int, error) {
+	return 0, errors.New("default not a terminal")
+}
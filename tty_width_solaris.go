@@ -0,0 +1,17 @@
+// +build solaris
+// +build !appengine
+
+package logftext
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func terminalWidth(fd uintptr) (int, error) {
+	ws, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(ws.Col), nil
+}
@@ -0,0 +1,26 @@
+// +build darwin freebsd openbsd netbsd dragonfly linux
+// +build !appengine
+
+package logftext
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type winsize struct {
+	Row    uint16
+	Col    uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+func terminalWidth(fd uintptr) (int, error) {
+	var ws winsize
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, fd, syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)), 0, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	return int(ws.Col), nil
+}
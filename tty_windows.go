@@ -2,11 +2,13 @@ package logftext
 
 import (
 	"syscall"
+	"unsafe"
 )
 
 var (
-	kernel32       *syscall.LazyDLL  = syscall.NewLazyDLL("Kernel32.dll")
-	setConsoleMode *syscall.LazyProc = kernel32.NewProc("SetConsoleMode")
+	kernel32                   *syscall.LazyDLL  = syscall.NewLazyDLL("Kernel32.dll")
+	setConsoleMode             *syscall.LazyProc = kernel32.NewProc("SetConsoleMode")
+	getConsoleScreenBufferInfo *syscall.LazyProc = kernel32.NewProc("GetConsoleScreenBufferInfo")
 )
 
 // enableVirtualTerminalProcessing enables virtual terminal sequences.
@@ -36,3 +38,34 @@ func enableSeqTTY(fd uintptr, flag bool) error {
 
 	return nil
 }
+
+type coord struct {
+	X int16
+	Y int16
+}
+
+type smallRect struct {
+	Left   int16
+	Top    int16
+	Right  int16
+	Bottom int16
+}
+
+type consoleScreenBufferInfo struct {
+	Size              coord
+	CursorPosition    coord
+	Attributes        uint16
+	Window            smallRect
+	MaximumWindowSize coord
+}
+
+// terminalWidth returns the width of the console window.
+func terminalWidth(fd uintptr) (int, error) {
+	var info consoleScreenBufferInfo
+	r, _, errno := getConsoleScreenBufferInfo.Call(fd, uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, errno
+	}
+
+	return int(info.Window.Right-info.Window.Left) + 1, nil
+}
@@ -0,0 +1,124 @@
+package logftext
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// wideRanges holds ranges of East Asian wide and fullwidth characters and
+// emoji that take two terminal cells.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},   // Hangul Jamo
+	{0x231a, 0x231b},   // Watch, hourglass
+	{0x2329, 0x232a},   // Angle brackets
+	{0x23e9, 0x23ec},   // Media controls
+	{0x23f0, 0x23f0},   // Alarm clock
+	{0x23f3, 0x23f3},   // Hourglass
+	{0x25fd, 0x25fe},   // Small squares
+	{0x2614, 0x2615},   // Umbrella, hot beverage
+	{0x2648, 0x2653},   // Zodiac
+	{0x26a1, 0x26a1},   // High voltage
+	{0x26aa, 0x26ab},   // Circles
+	{0x26bd, 0x26be},   // Soccer ball, baseball
+	{0x26c4, 0x26c5},   // Snowman, sun
+	{0x26d4, 0x26d4},   // No entry
+	{0x26ea, 0x26ea},   // Church
+	{0x26f2, 0x26f5},   // Fountain, golf, sailboat
+	{0x26fa, 0x26fa},   // Tent
+	{0x26fd, 0x26fd},   // Fuel pump
+	{0x2705, 0x2705},   // Check mark
+	{0x270a, 0x270b},   // Fists
+	{0x2728, 0x2728},   // Sparkles
+	{0x274c, 0x274c},   // Cross mark
+	{0x2753, 0x2755},   // Question marks
+	{0x2757, 0x2757},   // Exclamation mark
+	{0x2795, 0x2797},   // Plus, minus, division
+	{0x27b0, 0x27b0},   // Curly loop
+	{0x27bf, 0x27bf},   // Double curly loop
+	{0x2b1b, 0x2b1c},   // Large squares
+	{0x2b50, 0x2b50},   // Star
+	{0x2b55, 0x2b55},   // Circle
+	{0x2e80, 0x303e},   // CJK radicals, symbols and punctuation
+	{0x3041, 0x33ff},   // Hiragana, Katakana, CJK compatibility
+	{0x3400, 0x4dbf},   // CJK unified ideographs extension A
+	{0x4e00, 0x9fff},   // CJK unified ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xa960, 0xa97f},   // Hangul Jamo extended A
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe10, 0xfe19},   // Vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small forms
+	{0xff00, 0xff60},   // Fullwidth forms
+	{0xffe0, 0xffe6},   // Fullwidth signs
+	{0x16fe0, 0x18cff}, // Tangut, Khitan
+	{0x1b000, 0x1b2ff}, // Kana supplement, Nushu
+	{0x1f004, 0x1f004}, // Mahjong tile
+	{0x1f0cf, 0x1f0cf}, // Playing card
+	{0x1f18e, 0x1f18e}, // AB button
+	{0x1f191, 0x1f19a}, // Squared words
+	{0x1f200, 0x1f2ff}, // Enclosed ideographic supplement
+	{0x1f300, 0x1f64f}, // Pictographs, emoticons
+	{0x1f680, 0x1f6ff}, // Transport and map symbols
+	{0x1f7e0, 0x1f7eb}, // Large colored circles and squares
+	{0x1f90c, 0x1f9ff}, // Supplemental symbols and pictographs
+	{0x1fa70, 0x1faff}, // Symbols and pictographs extended A
+	{0x20000, 0x2fffd}, // CJK unified ideographs extensions B-F
+	{0x30000, 0x3fffd}, // CJK unified ideographs extension G
+}
+
+// runeWidth returns the number of terminal cells the given rune takes:
+// zero for combining marks and other zero-width characters, two for wide
+// characters and one for all others.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x300:
+		return 1
+	case r == 0x200b || r == 0x200c || r == 0x200d || r == 0x2060 || r == 0xfeff:
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return 0
+	}
+
+	lo, hi := 0, len(wideRanges)
+	for lo < hi {
+		m := (lo + hi) / 2
+		switch {
+		case r < wideRanges[m][0]:
+			hi = m
+		case r > wideRanges[m][1]:
+			lo = m + 1
+		default:
+			return 2
+		}
+	}
+
+	return 1
+}
+
+// stringWidth returns the number of terminal cells the given text takes.
+func stringWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+
+	return n
+}
+
+// visibleWidth returns the number of terminal cells the given text takes
+// without escape sequences.
+func visibleWidth(text []byte) int {
+	n := 0
+	for i := 0; i < len(text); {
+		if l := escapeLen(text[i:]); l != 0 {
+			i += l
+
+			continue
+		}
+		r, l := utf8.DecodeRune(text[i:])
+		i += l
+		n += runeWidth(r)
+	}
+
+	return n
+}
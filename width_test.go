@@ -0,0 +1,30 @@
+package logftext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisibleWidth(t *testing.T) {
+	testCases := []struct {
+		Text  string
+		Width int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"\x1b[31mabc\x1b[0m", 3},
+		{"\x1b]8;;file:///a\x1b\\link\x1b]8;;\x1b\\", 4},
+		{"日本語", 6},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},
+		{"é", 1},
+		{"a‍b", 2},
+		{"🚀!", 3},
+		{"…", 1},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.Width, visibleWidth([]byte(tc.Text)), tc.Text)
+	}
+}